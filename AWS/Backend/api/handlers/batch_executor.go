@@ -1,225 +1,312 @@
 package handlers
 
 import (
-	"bufio"
+	"Backend/api/handlers/console"
+	"Backend/api/handlers/usermanag"
+	"context"
 	"fmt"
-	"os"
+	"io"
 	"strings"
 	"time"
 )
 
+// UnknownCommands cuenta los comandos con StatusUnknown: no son fallos
+// conocidos, así que no detienen el lote ni deshacen la transacción.
 type BatchExecuteResult struct {
 	TotalCommands   int                      `json:"total_commands"`
 	SuccessCommands int                      `json:"success_commands"`
 	FailedCommands  int                      `json:"failed_commands"`
+	UnknownCommands int                      `json:"unknown_commands"`
 	Results         []CommandExecutionResult `json:"results"`
 	ExecutionTime   string                   `json:"execution_time"`
 	Success         bool                     `json:"success"`
+	Policy          ErrorPolicy              `json:"policy"`
+	StopReason      string                   `json:"stop_reason"`
+	StopDetail      string                   `json:"stop_detail,omitempty"`
+	StoppedAtLine   int                      `json:"stopped_at_line,omitempty"`
+	Transactional   bool                     `json:"transactional"`
+	Transaction     string                   `json:"transaction,omitempty"`
+	RollbackError   string                   `json:"rollback_error,omitempty"`
 }
 
 type CommandExecutionResult struct {
-	Command       string `json:"command"`
-	Output        string `json:"output"`
-	Success       bool   `json:"success"`
-	Error         string `json:"error,omitempty"`
-	LineNumber    int    `json:"line_number"`
-	ExecutionTime string `json:"execution_time"`
+	Command       string        `json:"command"`
+	Output        string        `json:"output"`
+	Success       bool          `json:"success"`
+	Error         string        `json:"error,omitempty"`
+	Status        CommandStatus `json:"status"`
+	Code          ErrorCode     `json:"code,omitempty"`
+	Message       string        `json:"message,omitempty"`
+	Data          CommandData   `json:"data"`
+	LineNumber    int           `json:"line_number"`
+	ExecutionTime string        `json:"execution_time"`
+	Policy        string        `json:"policy,omitempty"`
 }
 
-func ExecuteBatchFromFile(filePath string) (*BatchExecuteResult, error) {
-	startTime := time.Now()
-
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("no se pudo abrir archivo: %v", err)
-	}
-	defer file.Close()
-
-	result := &BatchExecuteResult{
-		Results: []CommandExecutionResult{},
-	}
+type batchLine struct {
+	Number  int
+	Command string
+	Comment string
+}
 
-	scanner := bufio.NewScanner(file)
-	lineNumber := 0
+type batchHooks struct {
+	onStart  func(line batchLine)
+	output   func(line batchLine) io.Writer
+	onFinish func(result CommandExecutionResult)
+}
 
-	for scanner.Scan() {
-		lineNumber++
-		line := strings.TrimSpace(scanner.Text())
+type batchOptions struct {
+	ctx    context.Context
+	policy ErrorPolicy
+	pause  time.Duration
+	hooks  batchHooks
+	// coreLocked indica que quien llama ya tiene coreMu en escritura.
+	coreLocked bool
+	// transactional restaura los discos y montajes si el lote falla.
+	transactional bool
+}
 
-		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
-			continue
-		}
+func ExecuteBatchFromFile(caller *usermanag.Caller, filePath string, policy ErrorPolicy, transactional bool) (*BatchExecuteResult, error) {
+	lines, err := readBatchFile(caller, filePath)
+	if err != nil {
+		return nil, err
+	}
 
-		cmdResult := executeSingleCommandForBatch(line, lineNumber)
-		result.Results = append(result.Results, cmdResult)
-		result.TotalCommands++
+	return runBatch(caller, lines, batchOptions{
+		policy:        policy,
+		pause:         100 * time.Millisecond,
+		transactional: transactional,
+	}), nil
+}
 
-		if cmdResult.Success {
-			result.SuccessCommands++
-		} else {
-			result.FailedCommands++
+func readBatchFile(caller *usermanag.Caller, filePath string) ([]batchLine, error) {
+	content, err := readScriptFile(caller, filePath)
+	if err != nil {
+		return nil, err
+	}
 
-			if isCriticalError(line, cmdResult.Error) {
-				fmt.Printf("Error crítico en línea %d, deteniendo ejecución\n", lineNumber)
-				break
-			}
-		}
+	return lexBatchLines(content)
+}
 
-		time.Sleep(100 * time.Millisecond)
+func lexBatchLines(script string) ([]batchLine, error) {
+	statements, err := LexScript(script)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error leyendo archivo: %v", err)
+	lines := make([]batchLine, 0, len(statements))
+	for _, statement := range statements {
+		lines = append(lines, batchLine{
+			Number:  statement.Line,
+			Command: statement.Command,
+			Comment: statement.Comment,
+		})
 	}
 
-	result.ExecutionTime = time.Since(startTime).String()
-	result.Success = result.FailedCommands == 0
-
-
-	return result, nil
+	return lines, nil
 }
 
-func executeSingleCommandForBatch(command string, lineNumber int) CommandExecutionResult {
-	startTime := time.Now()
-
-	output, success := executeCommandInternal(command)
-
-	executionTime := time.Since(startTime)
+func runBatch(caller *usermanag.Caller, lines []batchLine, opts batchOptions) *BatchExecuteResult {
+	if !opts.transactional {
+		return runBatchLines(caller, lines, opts)
+	}
 
-	status := "✅"
-	if !success {
-		status = "❌"
+	// Nadie más puede ejecutar comandos mientras dure la transacción: sus
+	// cambios se perderían en un rollback.
+	if !opts.coreLocked {
+		coreMu.Lock()
+		defer coreMu.Unlock()
+		opts.coreLocked = true
 	}
-	fmt.Printf("[Línea %d] %s COMPLETADO en %s: %s\n", lineNumber, status, executionTime, command)
 
-	if len(output) > 0 {
-		preview := strings.Split(output, "\n")[0]
-		if len(preview) > 100 {
-			preview = preview[:100] + "..."
+	tx, err := beginDiskTransaction(caller, lines)
+	if err != nil {
+		console.Printf("No se pudo iniciar la transacción: %v\n", err)
+		return &BatchExecuteResult{
+			Results:       []CommandExecutionResult{},
+			Policy:        opts.policy,
+			StopReason:    StopError,
+			StopDetail:    fmt.Sprintf("no se pudo iniciar la transacción: %v", err),
+			Transactional: true,
+			Transaction:   TxNotStarted,
 		}
 	}
 
-	result := CommandExecutionResult{
-		Command:       command,
-		Output:        output,
-		Success:       success,
-		LineNumber:    lineNumber,
-		ExecutionTime: executionTime.String(),
+	result := runBatchLines(caller, lines, opts)
+	result.Transactional = true
+
+	if result.Success {
+		tx.commit()
+		result.Transaction = TxCommitted
+		return result
 	}
 
-	time.Sleep(50 * time.Millisecond)
+	if err := tx.rollback(); err != nil {
+		result.Transaction = TxRollbackFailed
+		result.RollbackError = err.Error()
+		return result
+	}
 
+	result.Transaction = TxRolledBack
 	return result
 }
 
-func isCriticalError(command, errorMsg string) bool {
-	cmd := strings.ToLower(strings.TrimSpace(command))
+func runBatchLines(caller *usermanag.Caller, lines []batchLine, opts batchOptions) *BatchExecuteResult {
+	startTime := time.Now()
 
-	nonCriticalErrors := []string{
-		"archivo ya existe",
-		"partición ya está montada",
-		"usuario ya existe",
-		"grupo ya existe",
-		"no existe",                 
-		"no encontrado",             
-		"no hay sesión",            
-		"usuario no tiene permisos", 
-		"credenciales incorrectas",  
-		"directorio no creado",     
-		"no existe la carpeta",      
-		"usuario eliminado",         
-		"grupo eliminado",           
-		"usuario repetido",          
-	}
-
-	for _, nonCritical := range nonCriticalErrors {
-		if strings.Contains(strings.ToLower(errorMsg), nonCritical) {
-			return false
-		}
+	result := &BatchExecuteResult{
+		Results:    []CommandExecutionResult{},
+		Policy:     opts.policy,
+		StopReason: StopCompleted,
 	}
 
-	if strings.HasPrefix(cmd, "mkdisk") {
-		systemErrors := []string{
-			"no se pudo crear",
-			"error de escritura",
-			"sin espacio",
-			"permisos insuficientes",
+	for _, line := range lines {
+		if opts.ctx != nil && opts.ctx.Err() != nil {
+			console.Printf("Lote cancelado antes de la línea %d\n", line.Number)
+			result.StopReason = StopCancelled
+			result.StopDetail = fmt.Sprintf("cancelado antes de la línea %d", line.Number)
+			result.StoppedAtLine = line.Number
+			break
+		}
+
+		override, directiveErr := policyDirective(line.Comment)
+
+		if opts.hooks.onStart != nil {
+			opts.hooks.onStart(line)
+		}
+
+		sink := io.Discard
+		if opts.hooks.output != nil {
+			sink = opts.hooks.output(line)
 		}
 
-		for _, sysError := range systemErrors {
-			if strings.Contains(strings.ToLower(errorMsg), sysError) {
-				return true
+		var cmdResult CommandExecutionResult
+		if directiveErr != nil {
+			cmdResult = CommandExecutionResult{
+				Command:    line.Command,
+				Success:    false,
+				Error:      directiveErr.Error(),
+				Status:     StatusFailed,
+				Code:       ErrInvalidParameters,
+				Message:    directiveErr.Error(),
+				LineNumber: line.Number,
 			}
+		} else {
+			cmdResult = executeSingleCommandForBatch(caller, sink, line.Command, line.Number, opts.coreLocked)
 		}
 
-		return false
-	}
+		policy := opts.policy
+		if override != "" {
+			policy = ErrorPolicy{Mode: override}
+		}
+		cmdResult.Policy = policy.String()
 
-	if strings.HasPrefix(cmd, "rmdisk") ||
-		(strings.HasPrefix(cmd, "fdisk") && strings.Contains(cmd, "-delete")) ||
-		strings.HasPrefix(cmd, "unmount") {
+		result.Results = append(result.Results, cmdResult)
+		result.TotalCommands++
 
-		if strings.Contains(strings.ToLower(errorMsg), "error de sistema") ||
-			strings.Contains(strings.ToLower(errorMsg), "archivo corrupto") {
-			return true
+		if opts.hooks.onFinish != nil {
+			opts.hooks.onFinish(cmdResult)
 		}
 
-		return false
+		if cmdResult.Success {
+			result.SuccessCommands++
+		} else if cmdResult.Status == StatusUnknown {
+			result.UnknownCommands++
+		} else {
+			result.FailedCommands++
+
+			if policy.ShouldStop(cmdResult.Code) {
+				console.Printf("Error %s en línea %d, deteniendo ejecución (política %s)\n",
+					cmdResult.Code, line.Number, policy)
+				result.StopReason = StopError
+				result.StopDetail = fmt.Sprintf("error %s en línea %d con política %s: %s",
+					cmdResult.Code, line.Number, policy, cmdResult.Message)
+				result.StoppedAtLine = line.Number
+				break
+			}
+		}
+
+		time.Sleep(opts.pause)
 	}
 
-	return false
-}
+	result.ExecutionTime = time.Since(startTime).String()
+	result.Success = result.FailedCommands == 0 && result.StopReason != StopCancelled
 
-func ExecuteBatchFromString(commandsString string) (*BatchExecuteResult, error) {
+	return result
+}
 
+func executeSingleCommandForBatch(caller *usermanag.Caller, sink io.Writer, command string, lineNumber int, coreLocked bool) CommandExecutionResult {
 	startTime := time.Now()
 
-	var commands []string
-	if strings.Contains(commandsString, ";") {
-		commands = strings.Split(commandsString, ";")
-	} else {
-		commands = strings.Split(commandsString, "\n")
+	run := runCommand
+	if coreLocked {
+		run = runCommandLocked
 	}
+	cmdResult := run(caller, sink, command)
+	outcome := cmdResult.Outcome
 
-	result := &BatchExecuteResult{
-		Results: []CommandExecutionResult{},
+	executionTime := time.Since(startTime)
+
+	status := "✅"
+	switch outcome.Status {
+	case StatusFailed:
+		status = "❌"
+	case StatusUnknown:
+		status = "❔"
 	}
+	console.Printf("[Línea %d] %s COMPLETADO en %s: %s\n", lineNumber, status, executionTime, command)
 
-	for i, command := range commands {
-		command = strings.TrimSpace(command)
-		if command == "" {
-			continue
-		}
+	result := CommandExecutionResult{
+		Command:       command,
+		Output:        cmdResult.Output,
+		Success:       cmdResult.Success,
+		Status:        outcome.Status,
+		Code:          outcome.Code,
+		Message:       outcome.Message,
+		Data:          outcome.Data,
+		LineNumber:    lineNumber,
+		ExecutionTime: executionTime.String(),
+	}
 
+	if outcome.Status == StatusFailed {
+		result.Error = outcome.Message
+	}
 
-		cmdResult := executeSingleCommandForBatch(command, i+1)
-		result.Results = append(result.Results, cmdResult)
-		result.TotalCommands++
+	time.Sleep(50 * time.Millisecond)
 
-		if cmdResult.Success {
-			result.SuccessCommands++
-		} else {
-			result.FailedCommands++
-		}
+	return result
+}
 
-		time.Sleep(50 * time.Millisecond)
+func ExecuteBatchFromString(caller *usermanag.Caller, commandsString string, policy ErrorPolicy, transactional bool) (*BatchExecuteResult, error) {
+	lines, err := splitBatchString(commandsString)
+	if err != nil {
+		return nil, err
 	}
 
-	result.ExecutionTime = time.Since(startTime).String()
-	result.Success = result.FailedCommands == 0
+	return runBatch(caller, lines, batchOptions{
+		policy:        policy,
+		pause:         50 * time.Millisecond,
+		transactional: transactional,
+	}), nil
+}
 
-	return result, nil
+func splitBatchString(commandsString string) ([]batchLine, error) {
+	return lexBatchLines(commandsString)
 }
 
 func ParseExecuteCommand(command string) (string, bool, bool) {
-	parts := strings.Fields(command)
+	statement, err := LexCommand(command)
+	if err != nil {
+		return "", false, false
+	}
+
 	var path string
 	verbose := false
 
-	for _, part := range parts {
-		if strings.HasPrefix(part, "-path=") {
-			path = strings.TrimPrefix(part, "-path=")
-			path = strings.Trim(path, "\"'")
+	for _, token := range statement.Tokens {
+		part := token.Text
+		if strings.HasPrefix(strings.ToLower(part), "-path=") {
+			path = part[len("-path="):]
 		} else if part == "-v" || part == "-verbose" {
 			verbose = true
 		}
@@ -234,5 +321,6 @@ func IsBatchCommand(command string) bool {
 }
 
 func IsMultiCommand(command string) bool {
-	return strings.Contains(command, ";") && strings.Count(command, ";") >= 1
+	statements, err := LexScript(command)
+	return err == nil && len(statements) > 1
 }
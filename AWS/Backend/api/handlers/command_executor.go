@@ -2,100 +2,94 @@ package handlers
 
 import (
 	"Backend/Analyzer"
-	"fmt"
+	"Backend/api/handlers/console"
+	"Backend/api/handlers/usermanag"
+	"bytes"
 	"io"
-	"os"
-	"strings"
+	"net/http"
+	"sync"
 )
 
+// coreMu protege el directorio de discos y la tabla de montajes: los
+// comandos y los endpoints que leen discos o montajes lo toman en lectura, y
+// las simulaciones, que los sustituyen durante todo el lote, en escritura.
+// Los paquetes disk, filemanag y usermanag no lo ven; sus endpoints se
+// registran con ReadsCore.
+var coreMu sync.RWMutex
+
+// ReadsCore atiende la petición con coreMu tomado en lectura. h no debe
+// tomar coreMu por su cuenta.
+func ReadsCore(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		coreMu.RLock()
+		defer coreMu.RUnlock()
+
+		h(w, r)
+	}
+}
+
 type CommandResult struct {
 	Output  string
 	Success bool
+	Outcome CommandOutcome
 }
 
-func executeCommandInternal(command string) (string, bool) {
-	if IsSafeCommand(command) {
-		return ExecuteSafeCommand(command)
-	}
+// runCommand ejecuta un comando con la sesión del cliente. La salida se
+// devuelve completa y además se copia a sink al terminar.
+func runCommand(caller *usermanag.Caller, sink io.Writer, command string) CommandResult {
+	coreMu.RLock()
+	defer coreMu.RUnlock()
 
-	return executeNormalCommand(command)
+	return runCommandLocked(caller, sink, command)
 }
 
-func executeNormalCommand(command string) (string, bool) {
-	oldStdout := os.Stdout
-	r, w, err := os.Pipe()
-	if err != nil {
-		return fmt.Sprintf("Error interno: %v", err), false
-	}
-
-	os.Stdout = w
-
-	outputChan := make(chan string, 1)
-	go func() {
-		defer r.Close()
-		output, _ := io.ReadAll(r)
-		outputChan <- string(output)
-	}()
-
-	Analyzer.ProcessCommand(command)
-
-	w.Close()
-	os.Stdout = oldStdout
-
-	output := <-outputChan
-
-	success := determineCommandSuccess(command, output)
-
-	return output, success
+// runCommandLocked requiere coreMu tomado. La salida se captura en memoria
+// y se copia a sink cuando el comando termina, ya sin la sesión global ni
+// la captura de os.Stdout tomadas.
+func runCommandLocked(caller *usermanag.Caller, sink io.Writer, command string) CommandResult {
+	var result CommandResult
+
+	var buf bytes.Buffer
+	caller.Run(func() {
+		probe := newCommandProbe(command)
+
+		// execute hace que el núcleo lea un archivo del servidor: sin sesión
+		// o fuera del directorio de scripts no se le pasa.
+		if probe.cmd.Name == "execute" {
+			if probe.code != "" {
+				result.Outcome = probe.finish("")
+				return
+			}
+			command = commandLine("execute", "path", probe.script)
+		}
+
+		if IsSafeCommand(command) {
+			output, ok := ExecuteSafeCommand(command)
+			buf.WriteString(output)
+
+			result.Outcome = probe.finish(output)
+			if !ok && result.Outcome.Success() {
+				result.Outcome.Status = StatusFailed
+				result.Outcome.Code = ErrCommandFailed
+				result.Outcome.Message = "el comando falló"
+			}
+		} else if err := console.Run(&buf, func() {
+			Analyzer.ProcessCommand(command)
+		}); err != nil {
+			result.Outcome = CommandOutcome{
+				Status:  StatusFailed,
+				Code:    ErrInternal,
+				Message: err.Error(),
+				Data:    CommandData{Command: probe.cmd.Name},
+			}
+		} else {
+			result.Outcome = probe.finish(buf.String())
+		}
+
+	})
+
+	result.Output = buf.String()
+	result.Success = result.Outcome.Success()
+	sink.Write(buf.Bytes())
+	return result
 }
-
-func determineCommandSuccess(command, output string) bool {
-	cmd := strings.ToLower(strings.TrimSpace(command))
-
-	if strings.Contains(output, "==========Error:") {
-		return false
-	}
-
-	switch {
-	case strings.HasPrefix(cmd, "mkdisk"):
-		return strings.Contains(output, "FIN COMANDO MKDISK") ||
-			strings.Contains(output, "Disco creado exitosamente") ||
-			!strings.Contains(output, "Error:")
-
-	case strings.HasPrefix(cmd, "fdisk"):
-		return !strings.Contains(output, "Error:") ||
-			strings.Contains(output, "Partición creada") ||
-			strings.Contains(output, "Partición eliminada")
-
-	case strings.HasPrefix(cmd, "mount"):
-		return !strings.Contains(output, "Error:") ||
-			strings.Contains(output, "montada exitosamente")
-
-	case strings.HasPrefix(cmd, "mkfs"):
-		return !strings.Contains(output, "Error:") ||
-			strings.Contains(output, "formateada exitosamente")
-
-	case strings.HasPrefix(cmd, "login"):
-		return !strings.Contains(output, "Error:") ||
-			strings.Contains(output, "Login exitoso")
-
-	case strings.HasPrefix(cmd, "logout"):
-		return !strings.Contains(output, "Error:") ||
-			strings.Contains(output, "Sesión cerrada")
-
-	case strings.HasPrefix(cmd, "mkgrp") || strings.HasPrefix(cmd, "mkusr"):
-		return !strings.Contains(output, "Error:")
-
-	case strings.HasPrefix(cmd, "mkdir") || strings.HasPrefix(cmd, "mkfile"):
-		return !strings.Contains(output, "Error:")
-
-	case strings.HasPrefix(cmd, "cat") || strings.HasPrefix(cmd, "find"):
-		return !strings.Contains(output, "Error:")
-
-	case strings.HasPrefix(cmd, "rep"):
-		return !strings.Contains(output, "Error:") ||
-			strings.Contains(output, "Reporte generado")
-	}
-
-	return !strings.Contains(output, "Error:")
-}
\ No newline at end of file
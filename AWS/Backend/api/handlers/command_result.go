@@ -0,0 +1,491 @@
+package handlers
+
+import (
+	"Backend/DiskManagement"
+	"Backend/UserManagement"
+	"Backend/Utils"
+	"Backend/api/handlers/disk"
+	"Backend/api/handlers/filemanag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type CommandStatus string
+
+const (
+	StatusSuccess CommandStatus = "success"
+	StatusFailed  CommandStatus = "failed"
+	// StatusUnknown indica que el comando cambió el disco sin imprimir un
+	// error, pero no hay forma de comprobar que hizo lo pedido.
+	StatusUnknown CommandStatus = "unknown"
+)
+
+type ErrorCode string
+
+const (
+	ErrInvalidCommand    ErrorCode = "INVALID_COMMAND"
+	ErrSyntax            ErrorCode = "SYNTAX_ERROR"
+	ErrInvalidParameters ErrorCode = "INVALID_PARAMETERS"
+	ErrNoSession         ErrorCode = "NO_SESSION"
+	ErrSessionActive     ErrorCode = "SESSION_ACTIVE"
+	ErrPermissionDenied  ErrorCode = "PERMISSION_DENIED"
+	ErrNotFound          ErrorCode = "NOT_FOUND"
+	ErrNotMounted        ErrorCode = "NOT_MOUNTED"
+	ErrAlreadyMounted    ErrorCode = "ALREADY_MOUNTED"
+	ErrCommandFailed     ErrorCode = "COMMAND_FAILED"
+	ErrNoSpace           ErrorCode = "NO_SPACE"
+	ErrPartitionLimit    ErrorCode = "PARTITION_LIMIT"
+	ErrDuplicateName     ErrorCode = "DUPLICATE_NAME"
+	ErrExtendedExists    ErrorCode = "EXTENDED_EXISTS"
+	ErrNoExtended        ErrorCode = "NO_EXTENDED"
+	ErrTargetExists      ErrorCode = "TARGET_EXISTS"
+	ErrNotEmpty          ErrorCode = "NOT_EMPTY"
+	ErrNoFreeInodes      ErrorCode = "NO_FREE_INODES"
+	ErrNoFreeBlocks      ErrorCode = "NO_FREE_BLOCKS"
+	ErrUnsupportedFS     ErrorCode = "UNSUPPORTED_FS"
+	ErrInternal          ErrorCode = "INTERNAL"
+)
+
+// knownErrorCodes son todos los códigos anteriores; un código nuevo debe
+// agregarse también aquí para que stop_on lo acepte.
+var knownErrorCodes = []ErrorCode{
+	ErrInvalidCommand,
+	ErrSyntax,
+	ErrInvalidParameters,
+	ErrNoSession,
+	ErrSessionActive,
+	ErrPermissionDenied,
+	ErrNotFound,
+	ErrNotMounted,
+	ErrAlreadyMounted,
+	ErrCommandFailed,
+	ErrNoSpace,
+	ErrPartitionLimit,
+	ErrDuplicateName,
+	ErrExtendedExists,
+	ErrNoExtended,
+	ErrTargetExists,
+	ErrNotEmpty,
+	ErrNoFreeInodes,
+	ErrNoFreeBlocks,
+	ErrUnsupportedFS,
+	ErrInternal,
+}
+
+type CommandData struct {
+	Command     string `json:"command"`
+	DiskPath    string `json:"disk_path,omitempty"`
+	PartitionID string `json:"partition_id,omitempty"`
+	Path        string `json:"path,omitempty"`
+	Inode       *int32 `json:"inode,omitempty"`
+}
+
+type CommandOutcome struct {
+	Status  CommandStatus `json:"status"`
+	Code    ErrorCode     `json:"code,omitempty"`
+	Message string        `json:"message"`
+	Data    CommandData   `json:"data"`
+}
+
+func (o CommandOutcome) Success() bool {
+	return o.Status == StatusSuccess
+}
+
+type diskStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+type stateSnapshot struct {
+	disks    map[string]diskStamp
+	mounted  map[string]DiskManagement.MountedPartition
+	loggedIn bool
+}
+
+// commandProbe guarda el estado antes de ejecutar un comando para decidir
+// después, comparándolo, si tuvo efecto y sobre qué disco, partición o inodo.
+type commandProbe struct {
+	cmd    parsedCommand
+	spec   commandSpec
+	known  bool
+	before stateSnapshot
+	code   ErrorCode
+	reason string
+
+	// partition es la partición de fdisk antes del comando, nil si no
+	// existía; report, el archivo de rep antes del comando.
+	partition *disk.ResolvedPartition
+	report    *diskStamp
+	// script es la ruta real del archivo de execute.
+	script string
+}
+
+func newCommandProbe(command string) *commandProbe {
+	cmd := parseCommandLine(command)
+	spec, known := commandSpecs[cmd.Name]
+
+	p := &commandProbe{
+		cmd:    cmd,
+		spec:   spec,
+		known:  known,
+		before: takeSnapshot(),
+	}
+	p.code, p.reason = p.checkPreconditions()
+
+	switch {
+	case cmd.Name == "fdisk":
+		p.partition, _ = disk.ResolvePartitionName(strings.ToUpper(cmd.Param("driveletter")), cmd.Param("name"))
+	case spec.Effect == effectReport:
+		p.report = statFile(cmd.Param("path"))
+	}
+
+	return p
+}
+
+// Requiere la sesión del cliente instalada (se llama dentro de Caller.Run).
+func (p *commandProbe) checkPreconditions() (ErrorCode, string) {
+	if !p.known {
+		return ErrInvalidCommand, fmt.Sprintf("comando desconocido: %s", p.cmd.Name)
+	}
+
+	for _, param := range p.spec.Required {
+		if !p.cmd.Has(param) {
+			return ErrInvalidParameters, fmt.Sprintf("falta el parámetro -%s", param)
+		}
+	}
+
+	if p.spec.NeedsSession && !p.before.loggedIn {
+		return ErrNoSession, "no hay sesión activa"
+	}
+
+	if p.spec.RootOnly && !UserManagement.CurrentSession.IsRoot {
+		return ErrPermissionDenied, "solo el usuario root puede ejecutar este comando"
+	}
+
+	switch p.cmd.Name {
+	case "execute":
+		script, err := resolveScriptPath(p.cmd.Param("path"))
+		if err != nil {
+			return ErrPermissionDenied, err.Error()
+		}
+		p.script = script
+	case "login":
+		if p.before.loggedIn {
+			return ErrSessionActive, "ya hay una sesión activa"
+		}
+		if _, ok := p.before.mounted[p.cmd.Param("id")]; !ok {
+			return ErrNotMounted, fmt.Sprintf("la partición %s no está montada", p.cmd.Param("id"))
+		}
+	case "mkfs", "unmount":
+		if _, ok := p.before.mounted[p.cmd.Param("id")]; !ok {
+			return ErrNotMounted, fmt.Sprintf("la partición %s no está montada", p.cmd.Param("id"))
+		}
+	case "rmdisk", "fdisk", "mount":
+		diskName := strings.ToUpper(p.cmd.Param("driveletter")) + ".dsk"
+		if _, ok := p.before.disks[diskName]; !ok {
+			return ErrNotFound, fmt.Sprintf("el disco %s no existe", diskName)
+		}
+		if p.cmd.Name == "mount" {
+			for _, mp := range p.before.mounted {
+				if filepath.Base(mp.Path) == diskName && strings.Trim(mp.Name, "\x00") == p.cmd.Param("name") {
+					return ErrAlreadyMounted, fmt.Sprintf("la partición %s ya está montada", p.cmd.Param("name"))
+				}
+			}
+		}
+	}
+
+	return "", ""
+}
+
+// finish arma el resultado a partir de la salida y del estado después del
+// comando. Analyzer.ProcessCommand no devuelve si el comando funcionó:
+//   - una línea "Error..." en la salida es un fallo para cualquier comando;
+//   - si el efecto se puede comprobar (disco creado o borrado, partición en
+//     el MBR, inodo de la ruta, tabla de montajes, sesión, reporte) el
+//     resultado es éxito o fallo según lo que se encuentre;
+//   - mkfs, mkgrp, mkusr, rmgrp, rmusr y chgrp solo dejan ver si el disco
+//     cambió: sin cambios es un fallo y con cambios StatusUnknown;
+//   - los comandos de solo lectura sin línea de error son exitosos.
+//
+// Requiere la sesión del cliente instalada.
+func (p *commandProbe) finish(output string) CommandOutcome {
+	after := takeSnapshot()
+	data := CommandData{Command: p.cmd.Name}
+
+	if p.code != "" {
+		return failedOutcome(p.code, p.reason, data)
+	}
+	if line, ok := coreErrorLine(output); ok {
+		return failedOutcome(ErrCommandFailed, line, data)
+	}
+
+	status := StatusFailed
+	switch p.spec.Effect {
+	case effectDisk:
+		status = p.verifyDisk(after, &data)
+	case effectMount:
+		if p.diffMounts(after, &data) {
+			status = StatusSuccess
+		}
+	case effectSession:
+		if after.loggedIn {
+			data.PartitionID = UserManagement.CurrentSession.PartitionID
+		}
+		if after.loggedIn == (p.cmd.Name == "login") && p.before.loggedIn != after.loggedIn {
+			status = StatusSuccess
+		}
+	case effectReport:
+		data.Path = p.cmd.Param("path")
+		data.PartitionID = p.cmd.Param("id")
+		// La fecha del sistema de archivos puede tener poca resolución: se
+		// compara con el archivo anterior y no con la hora de inicio.
+		if current := statFile(data.Path); current != nil && (p.report == nil || *current != *p.report) {
+			status = StatusSuccess
+		}
+	case effectNone:
+		status = StatusSuccess
+	}
+
+	switch status {
+	case StatusSuccess:
+		return CommandOutcome{
+			Status:  StatusSuccess,
+			Message: fmt.Sprintf("%s ejecutado correctamente", p.cmd.Name),
+			Data:    data,
+		}
+	case StatusUnknown:
+		return CommandOutcome{
+			Status:  StatusUnknown,
+			Message: fmt.Sprintf("%s modificó el disco sin informar errores, pero no se puede verificar el resultado", p.cmd.Name),
+			Data:    data,
+		}
+	}
+
+	return failedOutcome(ErrCommandFailed, "el comando no realizó cambios", data)
+}
+
+func failedOutcome(code ErrorCode, message string, data CommandData) CommandOutcome {
+	return CommandOutcome{
+		Status:  StatusFailed,
+		Code:    code,
+		Message: message,
+		Data:    data,
+	}
+}
+
+// verifyDisk comprueba el efecto de un comando que escribe en los discos.
+func (p *commandProbe) verifyDisk(after stateSnapshot, data *CommandData) CommandStatus {
+	changed := p.diffDisks(after, data)
+	diskName := strings.ToUpper(p.cmd.Param("driveletter")) + ".dsk"
+
+	switch p.cmd.Name {
+	case "mkdisk":
+		for name := range after.disks {
+			if _, existed := p.before.disks[name]; !existed {
+				data.DiskPath = filepath.Join(Utils.GetDiskDirectory(), name)
+				return StatusSuccess
+			}
+		}
+		return StatusFailed
+
+	case "rmdisk":
+		data.DiskPath = filepath.Join(Utils.GetDiskDirectory(), diskName)
+		if _, exists := after.disks[diskName]; exists {
+			return StatusFailed
+		}
+		return StatusSuccess
+
+	case "fdisk":
+		data.DiskPath = filepath.Join(Utils.GetDiskDirectory(), diskName)
+		return p.verifyPartition()
+	}
+
+	if !changed {
+		return StatusFailed
+	}
+
+	p.fillPartitionData(data)
+
+	switch p.cmd.Name {
+	case "mkdir", "mkfile":
+		if data.Inode == nil {
+			return StatusFailed
+		}
+		return StatusSuccess
+	}
+
+	return StatusUnknown
+}
+
+// verifyPartition compara la partición de fdisk en el disco con la que
+// había antes: creada, borrada o con otro tamaño según el modo.
+func (p *commandProbe) verifyPartition() CommandStatus {
+	driveLetter := strings.ToUpper(p.cmd.Param("driveletter"))
+	current, _ := disk.ResolvePartitionName(driveLetter, p.cmd.Param("name"))
+
+	switch {
+	case p.cmd.Has("delete"):
+		if p.partition != nil && current == nil {
+			return StatusSuccess
+		}
+	case p.cmd.Has("add"):
+		if p.partition != nil && current != nil && current.Record.Size != p.partition.Record.Size {
+			return StatusSuccess
+		}
+	default:
+		if p.partition == nil && current != nil {
+			return StatusSuccess
+		}
+	}
+
+	return StatusFailed
+}
+
+func (p *commandProbe) diffDisks(after stateSnapshot, data *CommandData) bool {
+	dir := Utils.GetDiskDirectory()
+
+	for name, stamp := range after.disks {
+		previous, existed := p.before.disks[name]
+		if !existed || previous != stamp {
+			data.DiskPath = filepath.Join(dir, name)
+			return true
+		}
+	}
+
+	for name := range p.before.disks {
+		if _, exists := after.disks[name]; !exists {
+			data.DiskPath = filepath.Join(dir, name)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *commandProbe) diffMounts(after stateSnapshot, data *CommandData) bool {
+	for id, mp := range after.mounted {
+		if _, existed := p.before.mounted[id]; !existed {
+			data.PartitionID = id
+			data.DiskPath = mp.Path
+			return true
+		}
+	}
+
+	for id, mp := range p.before.mounted {
+		if _, exists := after.mounted[id]; !exists {
+			data.PartitionID = id
+			data.DiskPath = mp.Path
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *commandProbe) fillPartitionData(data *CommandData) {
+	if id := p.cmd.Param("id"); id != "" {
+		data.PartitionID = id
+		return
+	}
+
+	if !p.spec.NeedsSession || !UserManagement.IsLoggedIn() {
+		return
+	}
+
+	data.PartitionID = UserManagement.CurrentSession.PartitionID
+
+	if p.cmd.Name != "mkdir" && p.cmd.Name != "mkfile" {
+		return
+	}
+
+	data.Path = p.cmd.Param("path")
+	if inode, ok := resolveInode(data.PartitionID, data.Path); ok {
+		data.Inode = &inode
+	}
+}
+
+func resolveInode(partitionID, path string) (int32, bool) {
+	partition, file, err := UserManagement.FindMountedPartitionForLoggedUser(partitionID)
+	if err != nil {
+		return -1, false
+	}
+	defer file.Close()
+
+	sb, err := UserManagement.ReadSuperblock(file, partition)
+	if err != nil {
+		return -1, false
+	}
+
+	inode, err := filemanag.FindDirectoryInode(file, sb, path)
+	if err != nil {
+		return -1, false
+	}
+
+	return inode, true
+}
+
+func statFile(path string) *diskStamp {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return nil
+	}
+	return &diskStamp{size: info.Size(), modTime: info.ModTime()}
+}
+
+func takeSnapshot() stateSnapshot {
+	snapshot := stateSnapshot{
+		disks:    make(map[string]diskStamp),
+		mounted:  make(map[string]DiskManagement.MountedPartition),
+		loggedIn: UserManagement.IsLoggedIn(),
+	}
+
+	if entries, err := os.ReadDir(Utils.GetDiskDirectory()); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".dsk") {
+				continue
+			}
+			if info, err := entry.Info(); err == nil {
+				snapshot.disks[entry.Name()] = diskStamp{size: info.Size(), modTime: info.ModTime()}
+			}
+		}
+	}
+
+	for _, partitions := range DiskManagement.GetMountedPartitions() {
+		for _, part := range partitions {
+			snapshot.mounted[strings.Trim(string(part.ID), "\x00")] = part
+		}
+	}
+
+	return snapshot
+}
+
+// coreErrorLine busca el mensaje de error que imprime el núcleo; se ignora el
+// contenido de archivos mostrado por cat.
+func coreErrorLine(output string) (string, bool) {
+	inCat := false
+
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.Contains(trimmed, "Start CAT"):
+			inCat = true
+			continue
+		case strings.Contains(trimmed, "End CAT"):
+			inCat = false
+			continue
+		case inCat:
+			continue
+		}
+
+		trimmed = strings.TrimLeft(trimmed, "= ")
+		if strings.HasPrefix(trimmed, "Error") {
+			return trimmed, true
+		}
+	}
+
+	return "", false
+}
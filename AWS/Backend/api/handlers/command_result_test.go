@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"Backend/UserManagement"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// probeFor arma un commandProbe con el estado actual como estado inicial y
+// sin los errores de precondición, que dependen del núcleo.
+func probeFor(command string) *commandProbe {
+	p := newCommandProbe(command)
+	p.code, p.reason = "", ""
+	return p
+}
+
+func TestCommandProbeFinish(t *testing.T) {
+	UserManagement.CurrentSession = UserManagement.Session{}
+	defer func() { UserManagement.CurrentSession = UserManagement.Session{} }()
+
+	report := filepath.Join(t.TempDir(), "mbr.png")
+
+	tests := []struct {
+		name    string
+		command string
+		output  string
+		setup   func(p *commandProbe)
+		status  CommandStatus
+		code    ErrorCode
+	}{
+		{name: "lectura sin error", command: "find -path=/ -name=*", output: "/docs\n", status: StatusSuccess},
+		{name: "error del núcleo", command: "cat -file1=/a.txt", output: "Error: no existe /a.txt\n", status: StatusFailed, code: ErrCommandFailed},
+		{name: "error dentro del archivo mostrado", command: "cat -file1=/a.txt",
+			output: "=== Start CAT ===\nError de ejemplo\n=== End CAT ===\n", status: StatusSuccess},
+		{name: "precondición", command: "mkgrp -name=g",
+			setup:  func(p *commandProbe) { p.code, p.reason = ErrNoSession, "no hay sesión activa" },
+			status: StatusFailed, code: ErrNoSession},
+		{name: "error en comando que escribe", command: "mkfs -id=A1", output: "Error: la partición no está montada\n",
+			setup:  func(p *commandProbe) { p.before.disks["zz-probe.dsk"] = diskStamp{} },
+			status: StatusFailed, code: ErrCommandFailed},
+		{name: "mkgrp sin cambios", command: "mkgrp -name=g", status: StatusFailed, code: ErrCommandFailed},
+		{name: "mkgrp con cambios", command: "mkgrp -name=g",
+			setup:  func(p *commandProbe) { p.before.disks["zz-probe.dsk"] = diskStamp{} },
+			status: StatusUnknown},
+		{name: "mkdisk sin disco nuevo", command: "mkdisk -size=5", status: StatusFailed, code: ErrCommandFailed},
+		{name: "rmdisk con el disco borrado", command: "rmdisk -driveletter=zz-probe",
+			setup:  func(p *commandProbe) { p.before.disks["ZZ-PROBE.dsk"] = diskStamp{} },
+			status: StatusSuccess},
+		{name: "mkdir sin inodo", command: "mkdir -path=/docs",
+			setup:  func(p *commandProbe) { p.before.disks["zz-probe.dsk"] = diskStamp{} },
+			status: StatusFailed, code: ErrCommandFailed},
+		{name: "login", command: "login -user=root -pass=123 -id=A1",
+			setup: func(p *commandProbe) {
+				UserManagement.CurrentSession = UserManagement.Session{Username: "root", PartitionID: "A1"}
+			},
+			status: StatusSuccess},
+		{name: "login sin sesión", command: "login -user=root -pass=123 -id=A1", status: StatusFailed, code: ErrCommandFailed},
+		{name: "reporte escrito", command: "rep -name=mbr -path=" + report + " -id=A1",
+			setup: func(p *commandProbe) {
+				if err := os.WriteFile(report, []byte("png"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			status: StatusSuccess},
+		{name: "reporte sin escribir", command: "rep -name=mbr -path=" + report + ".jpg -id=A1", status: StatusFailed, code: ErrCommandFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			UserManagement.CurrentSession = UserManagement.Session{}
+
+			p := probeFor(tt.command)
+			if tt.setup != nil {
+				tt.setup(p)
+			}
+
+			outcome := p.finish(tt.output)
+			if outcome.Status != tt.status || outcome.Code != tt.code {
+				t.Errorf("resultado = %s %s (%s), se esperaba %s %s", outcome.Status, outcome.Code, outcome.Message, tt.status, tt.code)
+			}
+			if outcome.Success() != (tt.status == StatusSuccess) {
+				t.Errorf("Success() = %t con estado %s", outcome.Success(), outcome.Status)
+			}
+		})
+	}
+}
+
+func TestCommandProbeErrorMessage(t *testing.T) {
+	outcome := probeFor("mkusr -user=ana -pass=1 -grp=g").finish("Procesando...\n= Error: el grupo g no existe\n")
+	if outcome.Message != "Error: el grupo g no existe" {
+		t.Errorf("mensaje = %q", outcome.Message)
+	}
+}
+
+func TestStatusRank(t *testing.T) {
+	if !(statusRank(StatusSuccess) < statusRank(StatusUnknown) && statusRank(StatusUnknown) < statusRank(StatusFailed)) {
+		t.Error("un fallo debe tener prioridad sobre un resultado sin verificar, y este sobre un éxito")
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import "strings"
+
+type commandEffect int
+
+const (
+	effectNone commandEffect = iota
+	effectDisk
+	effectMount
+	effectSession
+	effectReport
+)
+
+type commandSpec struct {
+	Required     []string
+	Optional     []string
+	Flags        []string
+	Values       map[string][]string
+	NeedsSession bool
+	RootOnly     bool
+	Effect       commandEffect
+}
+
+var (
+	unitsMkdisk = []string{"K", "M"}
+	unitsFdisk  = []string{"B", "K", "M"}
+	fitValues   = []string{"BF", "FF", "WF"}
+)
+
+// commandSpecs describe los comandos que entiende Analyzer: parámetros,
+// valores aceptados y qué estado modifican, para poder validar un script y
+// verificar el resultado sin leer la salida.
+var commandSpecs = map[string]commandSpec{
+	"mkdisk": {Required: []string{"size"}, Optional: []string{"unit", "fit"}, Effect: effectDisk,
+		Values: map[string][]string{"unit": unitsMkdisk, "fit": fitValues}},
+	"rmdisk": {Required: []string{"driveletter"}, Effect: effectDisk},
+	"fdisk": {Required: []string{"driveletter", "name"}, Optional: []string{"size", "unit", "type", "fit", "delete", "add"}, Effect: effectDisk,
+		Values: map[string][]string{"unit": unitsFdisk, "fit": fitValues, "type": {"P", "E", "L"}, "delete": {"FULL"}}},
+	"mount":   {Required: []string{"driveletter", "name"}, Effect: effectMount},
+	"unmount": {Required: []string{"id"}, Effect: effectMount},
+	"mounted": {Effect: effectNone},
+	"mkfs": {Required: []string{"id"}, Optional: []string{"type", "fs"}, Effect: effectDisk,
+		Values: map[string][]string{"type": {"FULL"}, "fs": {"2FS", "3FS"}}},
+	"login":   {Required: []string{"user", "pass", "id"}, Effect: effectSession},
+	"logout":  {NeedsSession: true, Effect: effectSession},
+	"mkgrp":   {Required: []string{"name"}, NeedsSession: true, RootOnly: true, Effect: effectDisk},
+	"rmgrp":   {Required: []string{"name"}, NeedsSession: true, RootOnly: true, Effect: effectDisk},
+	"mkusr":   {Required: []string{"user", "pass", "grp"}, NeedsSession: true, RootOnly: true, Effect: effectDisk},
+	"rmusr":   {Required: []string{"user"}, NeedsSession: true, RootOnly: true, Effect: effectDisk},
+	"chgrp":   {Required: []string{"user", "grp"}, NeedsSession: true, RootOnly: true, Effect: effectDisk},
+	"mkdir":   {Required: []string{"path"}, Flags: []string{"r"}, NeedsSession: true, Effect: effectDisk},
+	"mkfile":  {Required: []string{"path"}, Optional: []string{"size", "cont"}, Flags: []string{"r"}, NeedsSession: true, Effect: effectDisk},
+	"cat":     {Required: []string{"file1"}, NeedsSession: true, Effect: effectNone},
+	"find":    {Required: []string{"path", "name"}, NeedsSession: true, Effect: effectNone},
+	"rep":     {Required: []string{"name", "path", "id"}, Optional: []string{"ruta"}, Effect: effectReport},
+	"execute": {Required: []string{"path"}, NeedsSession: true, Effect: effectNone},
+}
+
+type parsedCommand struct {
+	Name   string
+	Params map[string]string
+}
+
+func parseCommandLine(command string) parsedCommand {
+	params := make(map[string]string)
+
+	statement, err := LexCommand(command)
+	if err != nil || len(statement.Tokens) == 0 {
+		fields := strings.Fields(command)
+		if len(fields) == 0 {
+			return parsedCommand{Params: params}
+		}
+		return parsedCommand{Name: strings.ToLower(fields[0]), Params: params}
+	}
+
+	for _, token := range statement.Tokens[1:] {
+		if !strings.HasPrefix(token.Text, "-") {
+			continue
+		}
+
+		key, value, _ := strings.Cut(token.Text[1:], "=")
+		params[strings.ToLower(key)] = value
+	}
+
+	return parsedCommand{
+		Name:   strings.ToLower(statement.Tokens[0].Text),
+		Params: params,
+	}
+}
+
+func (c parsedCommand) Param(name string) string {
+	return c.Params[name]
+}
+
+func (c parsedCommand) Has(name string) bool {
+	_, ok := c.Params[name]
+	return ok
+}
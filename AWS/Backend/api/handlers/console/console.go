@@ -0,0 +1,61 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// El núcleo (Analyzer, FileManagement...) imprime sus resultados con fmt en
+// os.Stdout. Run lo redirige hacia el destino de cada petición, y los logs
+// propios del servidor usan Printf/Println para no mezclarse con esa salida.
+var (
+	captureMu sync.Mutex
+	serverMu  sync.Mutex
+	serverOut = os.Stdout
+)
+
+// Run ejecuta fn enviando a sink todo lo que se escriba en os.Stdout mientras
+// dura. Las capturas se atienden de una en una, así dos peticiones
+// simultáneas nunca comparten salida. sink se escribe con el candado
+// tomado, por eso debe ser un búfer en memoria y no la red: un cliente
+// lento detendría a todos los demás.
+func Run(sink io.Writer, fn func()) error {
+	captureMu.Lock()
+	defer captureMu.Unlock()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("no se pudo redirigir la salida: %v", err)
+	}
+
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+		defer r.Close()
+		io.Copy(sink, r)
+	}()
+
+	os.Stdout = w
+	defer func() {
+		os.Stdout = serverOut
+		w.Close()
+		<-copied
+	}()
+
+	fn()
+	return nil
+}
+
+func Printf(format string, a ...interface{}) {
+	serverMu.Lock()
+	defer serverMu.Unlock()
+	fmt.Fprintf(serverOut, format, a...)
+}
+
+func Println(a ...interface{}) {
+	serverMu.Lock()
+	defer serverMu.Unlock()
+	fmt.Fprintln(serverOut, a...)
+}
@@ -0,0 +1,83 @@
+package console
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRunCapturesStdout(t *testing.T) {
+	var sink bytes.Buffer
+	err := Run(&sink, func() {
+		fmt.Println("línea 1")
+		fmt.Print("línea 2")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := sink.String(); got != "línea 1\nlínea 2" {
+		t.Errorf("salida = %q", got)
+	}
+}
+
+func TestRunKeepsServerLogsOut(t *testing.T) {
+	logFile, err := os.CreateTemp(t.TempDir(), "server-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logFile.Close()
+
+	serverMu.Lock()
+	saved := serverOut
+	serverOut = logFile
+	serverMu.Unlock()
+	defer func() {
+		serverMu.Lock()
+		serverOut = saved
+		serverMu.Unlock()
+		os.Stdout = saved
+	}()
+
+	var sink bytes.Buffer
+	Run(&sink, func() {
+		fmt.Print("del comando")
+		Printf("del servidor")
+	})
+
+	if sink.String() != "del comando" {
+		t.Errorf("salida del comando = %q", sink.String())
+	}
+	if logged, _ := os.ReadFile(logFile.Name()); string(logged) != "del servidor" {
+		t.Errorf("log del servidor = %q", logged)
+	}
+}
+
+func TestRunConcurrent(t *testing.T) {
+	const runs = 8
+
+	var wg sync.WaitGroup
+	sinks := make([]bytes.Buffer, runs)
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			Run(&sinks[i], func() {
+				for j := 0; j < 50; j++ {
+					fmt.Printf("%d\n", i)
+				}
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range sinks {
+		want := strings.Repeat(fmt.Sprintf("%d\n", i), 50)
+		if sinks[i].String() != want {
+			t.Errorf("la salida %d se mezcló: %q", i, sinks[i].String())
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package disk
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/mux"
+)
+
+// Los bitmaps usan un byte por entrada. Según la versión del núcleo el byte
+// es el carácter '1'/'0' o el valor 1/0, así que se aceptan ambos.
+
+type BitmapRun struct {
+	Start  int32 `json:"start"`
+	Length int32 `json:"length"`
+	Used   bool  `json:"used"`
+}
+
+type BitmapInfo struct {
+	Offset         int64       `json:"offset"`
+	Total          int32       `json:"total"`
+	Used           int32       `json:"used"`
+	Free           int32       `json:"free"`
+	FreeRuns       int         `json:"free_runs"`
+	UsedRuns       int         `json:"used_runs"`
+	LargestFreeRun int32       `json:"largest_free_run"`
+	Fragmentation  float64     `json:"fragmentation"`
+	InvalidEntries []int32     `json:"invalid_entries,omitempty"`
+	Runs           []BitmapRun `json:"runs"`
+}
+
+type PartitionBitmaps struct {
+	PartitionID string     `json:"partition_id"`
+	Inodes      BitmapInfo `json:"inodes"`
+	Blocks      BitmapInfo `json:"blocks"`
+}
+
+func GetPartitionBitmaps(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	partitionID := mux.Vars(r)["id"]
+	file, sb, ok := openPartitionForInspect(w, partitionID)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	inodes, err := readBitmap(file, int64(sb.S_bm_inode_start), sb.S_inodes_count)
+	if err != nil {
+		http.Error(w, "Error leyendo bitmap de inodos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	blocks, err := readBitmap(file, int64(sb.S_bm_block_start), sb.S_blocks_count)
+	if err != nil {
+		http.Error(w, "Error leyendo bitmap de bloques: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(PartitionBitmaps{
+		PartitionID: partitionID,
+		Inodes:      summarizeBitmap(inodes, int64(sb.S_bm_inode_start)),
+		Blocks:      summarizeBitmap(blocks, int64(sb.S_bm_block_start)),
+	})
+}
+
+func readBitmap(file *os.File, offset int64, count int32) ([]byte, error) {
+	if count < 0 {
+		return nil, fmt.Errorf("cantidad de entradas inválida: %d", count)
+	}
+
+	data := make([]byte, count)
+	if _, err := file.ReadAt(data, offset); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func BitmapUsed(b byte) bool {
+	return b == '1' || b == 1
+}
+
+// BitmapValue devuelve el byte que marca una entrada como usada o libre
+// con el mismo estilo que ya tiene el bitmap.
+func BitmapValue(bitmap []byte, set bool) byte {
+	ascii := false
+	for _, b := range bitmap {
+		if b == '0' || b == '1' {
+			ascii = true
+			break
+		}
+		if b == 1 {
+			break
+		}
+	}
+
+	switch {
+	case ascii && set:
+		return '1'
+	case ascii:
+		return '0'
+	case set:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func bitmapValid(b byte) bool {
+	return b == '0' || b == '1' || b == 0 || b == 1
+}
+
+// summarizeBitmap agrupa las entradas en tramos consecutivos usados o
+// libres. La fragmentación es la fracción del espacio libre que queda fuera
+// del tramo libre más grande.
+func summarizeBitmap(data []byte, offset int64) BitmapInfo {
+	info := BitmapInfo{
+		Offset: offset,
+		Total:  int32(len(data)),
+		Runs:   []BitmapRun{},
+	}
+
+	for i, b := range data {
+		used := BitmapUsed(b)
+		if !bitmapValid(b) {
+			info.InvalidEntries = append(info.InvalidEntries, int32(i))
+		}
+
+		if used {
+			info.Used++
+		} else {
+			info.Free++
+		}
+
+		last := len(info.Runs) - 1
+		if last >= 0 && info.Runs[last].Used == used {
+			info.Runs[last].Length++
+			continue
+		}
+		info.Runs = append(info.Runs, BitmapRun{Start: int32(i), Length: 1, Used: used})
+	}
+
+	for _, run := range info.Runs {
+		if run.Used {
+			info.UsedRuns++
+			continue
+		}
+		info.FreeRuns++
+		if run.Length > info.LargestFreeRun {
+			info.LargestFreeRun = run.Length
+		}
+	}
+
+	if info.Free > 0 {
+		info.Fragmentation = float64(info.Free-info.LargestFreeRun) / float64(info.Free)
+	}
+
+	return info
+}
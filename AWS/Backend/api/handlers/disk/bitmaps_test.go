@@ -0,0 +1,120 @@
+package disk
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSummarizeBitmap(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          []byte
+		used          int32
+		largestFree   int32
+		fragmentation float64
+		runs          []BitmapRun
+		invalid       []int32
+	}{
+		{
+			name: "vacío",
+			data: []byte{},
+			runs: []BitmapRun{},
+		},
+		{
+			name:        "todo libre en ASCII",
+			data:        []byte("0000"),
+			largestFree: 4,
+			runs:        []BitmapRun{{Start: 0, Length: 4, Used: false}},
+		},
+		{
+			name: "todo usado en binario",
+			data: []byte{1, 1, 1},
+			used: 3,
+			runs: []BitmapRun{{Start: 0, Length: 3, Used: true}},
+		},
+		{
+			name:          "tramos alternados",
+			data:          []byte("1100100001"),
+			used:          4,
+			largestFree:   4,
+			fragmentation: 2.0 / 6.0,
+			runs: []BitmapRun{
+				{Start: 0, Length: 2, Used: true},
+				{Start: 2, Length: 2, Used: false},
+				{Start: 4, Length: 1, Used: true},
+				{Start: 5, Length: 4, Used: false},
+				{Start: 9, Length: 1, Used: true},
+			},
+		},
+		{
+			name:          "entradas inválidas cuentan como libres",
+			data:          []byte{'1', 'x', '0', 7},
+			used:          1,
+			largestFree:   3,
+			fragmentation: 0,
+			runs: []BitmapRun{
+				{Start: 0, Length: 1, Used: true},
+				{Start: 1, Length: 3, Used: false},
+			},
+			invalid: []int32{1, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := summarizeBitmap(tt.data, 128)
+
+			if info.Offset != 128 || info.Total != int32(len(tt.data)) {
+				t.Errorf("Offset/Total = %d/%d", info.Offset, info.Total)
+			}
+			if info.Used != tt.used || info.Free != int32(len(tt.data))-tt.used {
+				t.Errorf("Used/Free = %d/%d, se esperaba %d/%d", info.Used, info.Free, tt.used, int32(len(tt.data))-tt.used)
+			}
+			if info.LargestFreeRun != tt.largestFree {
+				t.Errorf("LargestFreeRun = %d, se esperaba %d", info.LargestFreeRun, tt.largestFree)
+			}
+			if diff := info.Fragmentation - tt.fragmentation; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("Fragmentation = %f, se esperaba %f", info.Fragmentation, tt.fragmentation)
+			}
+			if !reflect.DeepEqual(info.Runs, tt.runs) {
+				t.Errorf("Runs = %+v, se esperaba %+v", info.Runs, tt.runs)
+			}
+			if !reflect.DeepEqual(info.InvalidEntries, tt.invalid) {
+				t.Errorf("InvalidEntries = %v, se esperaba %v", info.InvalidEntries, tt.invalid)
+			}
+
+			usedRuns, freeRuns := 0, 0
+			for _, run := range tt.runs {
+				if run.Used {
+					usedRuns++
+				} else {
+					freeRuns++
+				}
+			}
+			if info.UsedRuns != usedRuns || info.FreeRuns != freeRuns {
+				t.Errorf("UsedRuns/FreeRuns = %d/%d, se esperaba %d/%d", info.UsedRuns, info.FreeRuns, usedRuns, freeRuns)
+			}
+		})
+	}
+}
+
+func TestBitmapValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		bitmap []byte
+		set    bool
+		want   byte
+	}{
+		{name: "ASCII usado", bitmap: []byte("0010"), set: true, want: '1'},
+		{name: "ASCII libre", bitmap: []byte("1"), set: false, want: '0'},
+		{name: "binario usado", bitmap: []byte{0, 1, 0}, set: true, want: 1},
+		{name: "todo en cero es binario", bitmap: []byte{0, 0}, set: true, want: 1},
+		{name: "binario libre", bitmap: []byte{1}, set: false, want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := BitmapValue(tt.bitmap, tt.set); got != tt.want {
+			t.Errorf("%s: BitmapValue = %d, se esperaba %d", tt.name, got, tt.want)
+		}
+	}
+}
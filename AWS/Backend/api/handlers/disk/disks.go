@@ -3,6 +3,7 @@ package disk
 import (
 	Structs "Backend/FileSystem"
 	"Backend/Utils"
+	"Backend/api/handlers/console"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -19,6 +20,8 @@ type DiskInfo struct {
 	CreationDate string `json:"creation_date"`
 	Signature    int32  `json:"signature"`
 	Partitions   int    `json:"partitions"`
+	SizeBytes    int64  `json:"size_bytes"`
+	CreatedAt    string `json:"created_at,omitempty"`
 }
 
 func GetAllDisks(w http.ResponseWriter, r *http.Request) {
@@ -31,7 +34,7 @@ func GetAllDisks(w http.ResponseWriter, r *http.Request) {
 		disks = []DiskInfo{}
 	}
 
-	fmt.Printf("Encontrados %d discos\n", len(disks))
+	console.Printf("Encontrados %d discos\n", len(disks))
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(disks)
@@ -42,18 +45,18 @@ func readDisksWithMBRInfo() []DiskInfo {
 
 	testDir := Utils.GetDiskDirectory()
 	if _, err := os.Stat(testDir); os.IsNotExist(err) {
-		fmt.Printf("Directorio %s no existe, retornando array vacío\n", testDir)
+		console.Printf("Directorio %s no existe, retornando array vacío\n", testDir)
 		return disks
 	}
 
 	files, err := os.ReadDir(testDir)
 	if err != nil {
-		fmt.Printf(" Error leyendo directorio: %v\n", err)
+		console.Printf(" Error leyendo directorio: %v\n", err)
 		return disks
 	}
 
 	if len(files) == 0 {
-		fmt.Printf("Directorio %s está vacío (no hay discos creados aún)\n", testDir)
+		console.Printf("Directorio %s está vacío (no hay discos creados aún)\n", testDir)
 		return disks
 	}
 
@@ -66,35 +69,35 @@ func readDisksWithMBRInfo() []DiskInfo {
 			diskInfo := readDiskMBRInfo(fullPath, diskID)
 			if diskInfo != nil {
 				disks = append(disks, *diskInfo)
-				fmt.Printf(" Disco %s procesado exitosamente: %s, %d particiones\n",
+				console.Printf(" Disco %s procesado exitosamente: %s, %d particiones\n",
 					diskID, diskInfo.Size, diskInfo.Partitions)
 			} else {
-				fmt.Printf(" No se pudo procesar disco %s, omitiendo\n", diskID)
+				console.Printf(" No se pudo procesar disco %s, omitiendo\n", diskID)
 			}
 		}
 	}
 
-	fmt.Printf(" Procesamiento completado: %d discos válidos\n", len(disks))
+	console.Printf(" Procesamiento completado: %d discos válidos\n", len(disks))
 	return disks
 }
 
 func readDiskMBRInfo(diskPath, diskID string) *DiskInfo {
 	file, err := os.OpenFile(diskPath, os.O_RDONLY, 0644)
 	if err != nil {
-		fmt.Printf(" Error abriendo disco %s: %v\n", diskID, err)
+		console.Printf(" Error abriendo disco %s: %v\n", diskID, err)
 		return nil
 	}
 	defer file.Close()
 
 	var mbr Structs.MRB
 	if err := Utils.ReadObject(file, &mbr, 0); err != nil {
-		fmt.Printf(" Error leyendo MBR de disco %s: %v\n", diskID, err)
+		console.Printf(" Error leyendo MBR de disco %s: %v\n", diskID, err)
 		return nil
 	}
 
 	fileInfo, err := file.Stat()
 	if err != nil {
-		fmt.Printf(" Error obteniendo info de archivo %s: %v\n", diskID, err)
+		console.Printf(" Error obteniendo info de archivo %s: %v\n", diskID, err)
 		return nil
 	}
 
@@ -103,7 +106,7 @@ func readDiskMBRInfo(diskPath, diskID string) *DiskInfo {
 		if mbr.Partitions[i].Size > 0 {
 			partitionCount++
 			partName := strings.Trim(string(mbr.Partitions[i].Name[:]), "\x00")
-			fmt.Printf("    Partición %d: %s (tamaño: %d)\n", i+1, partName, mbr.Partitions[i].Size)
+			console.Printf("    Partición %d: %s (tamaño: %d)\n", i+1, partName, mbr.Partitions[i].Size)
 		}
 	}
 
@@ -120,6 +123,8 @@ func readDiskMBRInfo(diskPath, diskID string) *DiskInfo {
 		CreationDate: creationDate,
 		Signature:    mbr.MbrSize,
 		Partitions:   partitionCount,
+		SizeBytes:    fileInfo.Size(),
+		CreatedAt:    FormatCoreTime(mbr.CreationDate[:]),
 	}
 }
 
@@ -0,0 +1,398 @@
+package disk
+
+import (
+	Structs "Backend/FileSystem"
+	"Backend/Utils"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Endpoints de solo lectura para revisar las estructuras tal como están en
+// el .dsk, sin pasar por los comandos del núcleo.
+
+const (
+	defaultHexLength = 256
+	maxHexLength     = 64 * 1024
+)
+
+type RawPartition struct {
+	Status      string `json:"status"`
+	Type        string `json:"type"`
+	Fit         string `json:"fit"`
+	Start       int32  `json:"start"`
+	Size        int32  `json:"size"`
+	Name        string `json:"name"`
+	Correlative int32  `json:"correlative"`
+	ID          string `json:"id"`
+}
+
+type RawMBR struct {
+	DiskID       string         `json:"disk_id"`
+	Offset       int64          `json:"offset"`
+	StructSize   int            `json:"struct_size"`
+	MbrSize      int32          `json:"mbr_size"`
+	CreationDate string         `json:"creation_date"`
+	Signature    int32          `json:"signature"`
+	Fit          string         `json:"fit"`
+	Partitions   []RawPartition `json:"partitions"`
+}
+
+type RawEBR struct {
+	DiskID     string `json:"disk_id"`
+	Offset     int64  `json:"offset"`
+	StructSize int    `json:"struct_size"`
+	Mount      string `json:"mount"`
+	Fit        string `json:"fit"`
+	Start      int32  `json:"start"`
+	Size       int32  `json:"size"`
+	Next       int32  `json:"next"`
+	Name       string `json:"name"`
+}
+
+type RawInode struct {
+	PartitionID string  `json:"partition_id"`
+	Index       int32   `json:"index"`
+	Offset      int64   `json:"offset"`
+	StructSize  int     `json:"struct_size"`
+	UID         int32   `json:"uid"`
+	GID         int32   `json:"gid"`
+	Size        int32   `json:"size"`
+	Atime       string  `json:"atime"`
+	Ctime       string  `json:"ctime"`
+	Mtime       string  `json:"mtime"`
+	Blocks      []int32 `json:"blocks"`
+	Type        string  `json:"type"`
+	Perm        string  `json:"perm"`
+}
+
+type RawFolderEntry struct {
+	Name  string `json:"name"`
+	Inode int32  `json:"inode"`
+}
+
+type RawBlock struct {
+	PartitionID string           `json:"partition_id"`
+	Index       int32            `json:"index"`
+	Offset      int64            `json:"offset"`
+	As          string           `json:"as"`
+	Entries     []RawFolderEntry `json:"entries,omitempty"`
+	Content     *string          `json:"content,omitempty"`
+	Pointers    []int32          `json:"pointers,omitempty"`
+	Hex         string           `json:"hex"`
+}
+
+type RawHex struct {
+	DiskID string `json:"disk_id"`
+	Offset int64  `json:"offset"`
+	Length int64  `json:"length"`
+	Hex    string `json:"hex"`
+	Dump   string `json:"dump"`
+}
+
+func InspectMBR(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	diskID := mux.Vars(r)["diskId"]
+	file, _, ok := openDiskForInspect(w, diskID)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	var mbr Structs.MRB
+	if err := Utils.ReadObject(file, &mbr, 0); err != nil {
+		http.Error(w, "Error leyendo MBR: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	raw := RawMBR{
+		DiskID:       diskID,
+		StructSize:   binary.Size(mbr),
+		MbrSize:      mbr.MbrSize,
+		CreationDate: trimName(mbr.CreationDate[:]),
+		Signature:    mbr.Signature,
+		Fit:          rawByte(mbr.Fit[0]),
+		Partitions:   []RawPartition{},
+	}
+	for _, partition := range mbr.Partitions {
+		raw.Partitions = append(raw.Partitions, RawPartition{
+			Status:      rawByte(partition.Status[0]),
+			Type:        rawByte(partition.Type[0]),
+			Fit:         rawByte(partition.Fit[0]),
+			Start:       partition.Start,
+			Size:        partition.Size,
+			Name:        trimName(partition.Name[:]),
+			Correlative: partition.Correlative,
+			ID:          trimName(partition.Id[:]),
+		})
+	}
+
+	json.NewEncoder(w).Encode(raw)
+}
+
+// InspectEBR decodifica un EBR en el offset indicado; no valida que el
+// offset pertenezca a la cadena de la extendida.
+func InspectEBR(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	diskID := mux.Vars(r)["diskId"]
+	offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+	if err != nil {
+		http.Error(w, "offset inválido", http.StatusBadRequest)
+		return
+	}
+
+	file, size, ok := openDiskForInspect(w, diskID)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	var ebr Structs.EBR
+	if offset < 0 || offset+int64(binary.Size(ebr)) > size {
+		http.Error(w, fmt.Sprintf("offset %d fuera del disco (%d bytes)", offset, size), http.StatusBadRequest)
+		return
+	}
+
+	if err := Utils.ReadObject(file, &ebr, offset); err != nil {
+		http.Error(w, "Error leyendo EBR: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(RawEBR{
+		DiskID:     diskID,
+		Offset:     offset,
+		StructSize: binary.Size(ebr),
+		Mount:      rawByte(ebr.PartMount),
+		Fit:        rawByte(ebr.PartFit),
+		Start:      ebr.PartStart,
+		Size:       ebr.PartSize,
+		Next:       ebr.PartNext,
+		Name:       trimName(ebr.PartName[:]),
+	})
+}
+
+func InspectHex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	diskID := mux.Vars(r)["diskId"]
+	query := r.URL.Query()
+
+	offset, err := strconv.ParseInt(query.Get("offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "offset inválido", http.StatusBadRequest)
+		return
+	}
+
+	length := int64(defaultHexLength)
+	if value := query.Get("length"); value != "" {
+		length, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || length <= 0 || length > maxHexLength {
+			http.Error(w, fmt.Sprintf("length debe estar entre 1 y %d", maxHexLength), http.StatusBadRequest)
+			return
+		}
+	}
+
+	file, size, ok := openDiskForInspect(w, diskID)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	if offset >= size {
+		http.Error(w, fmt.Sprintf("offset %d fuera del disco (%d bytes)", offset, size), http.StatusBadRequest)
+		return
+	}
+	if offset+length > size {
+		length = size - offset
+	}
+
+	data := make([]byte, length)
+	if _, err := file.ReadAt(data, offset); err != nil {
+		http.Error(w, "Error leyendo disco: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(RawHex{
+		DiskID: diskID,
+		Offset: offset,
+		Length: length,
+		Hex:    hex.EncodeToString(data),
+		Dump:   hex.Dump(data),
+	})
+}
+
+func InspectInode(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	file, sb, ok := openPartitionForInspect(w, vars["id"])
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	index, ok := parseIndex(w, vars["n"], sb.S_inodes_count, "inodo")
+	if !ok {
+		return
+	}
+
+	var inode Structs.Inode
+	offset := int64(sb.S_inode_start) + int64(index)*int64(sb.S_inode_size)
+	if err := Utils.ReadObject(file, &inode, offset); err != nil {
+		http.Error(w, "Error leyendo inodo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(RawInode{
+		PartitionID: vars["id"],
+		Index:       index,
+		Offset:      offset,
+		StructSize:  binary.Size(inode),
+		UID:         inode.I_uid,
+		GID:         inode.I_gid,
+		Size:        inode.I_size,
+		Atime:       trimName(inode.I_atime[:]),
+		Ctime:       trimName(inode.I_ctime[:]),
+		Mtime:       trimName(inode.I_mtime[:]),
+		Blocks:      inode.I_block[:],
+		Type:        rawByte(inode.I_type[0]),
+		Perm:        trimName(inode.I_perm[:]),
+	})
+}
+
+// InspectBlock lee el bloque N y lo interpreta según ?as=folder|file|pointer.
+// El sistema de archivos no guarda el tipo de cada bloque, así que la
+// interpretación la elige quien consulta.
+func InspectBlock(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	as := strings.ToLower(r.URL.Query().Get("as"))
+	if as == "" {
+		as = "file"
+	}
+	if as != "folder" && as != "file" && as != "pointer" {
+		http.Error(w, "as debe ser folder, file o pointer", http.StatusBadRequest)
+		return
+	}
+
+	file, sb, ok := openPartitionForInspect(w, vars["id"])
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	index, ok := parseIndex(w, vars["n"], sb.S_blocks_count, "bloque")
+	if !ok {
+		return
+	}
+
+	offset := int64(sb.S_block_start) + int64(index)*int64(sb.S_block_size)
+	raw := RawBlock{PartitionID: vars["id"], Index: index, Offset: offset, As: as}
+
+	var err error
+	switch as {
+	case "folder":
+		var block Structs.Folderblock
+		if err = Utils.ReadObject(file, &block, offset); err == nil {
+			for _, content := range block.B_content {
+				raw.Entries = append(raw.Entries, RawFolderEntry{
+					Name:  trimName(content.B_name[:]),
+					Inode: content.B_inodo,
+				})
+			}
+		}
+	case "pointer":
+		var block Structs.Pointerblock
+		if err = Utils.ReadObject(file, &block, offset); err == nil {
+			raw.Pointers = block.B_pointers[:]
+		}
+	default:
+		var block Structs.Fileblock
+		if err = Utils.ReadObject(file, &block, offset); err == nil {
+			content := trimName(block.B_content[:])
+			raw.Content = &content
+		}
+	}
+	if err != nil {
+		http.Error(w, "Error leyendo bloque: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := make([]byte, sb.S_block_size)
+	if _, err := file.ReadAt(data, offset); err == nil {
+		raw.Hex = hex.EncodeToString(data)
+	}
+
+	json.NewEncoder(w).Encode(raw)
+}
+
+func openDiskForInspect(w http.ResponseWriter, diskID string) (*os.File, int64, bool) {
+	diskPath := filepath.Join(Utils.GetDiskDirectory(), diskID+".dsk")
+
+	file, err := os.OpenFile(diskPath, os.O_RDONLY, 0644)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("disco %s no encontrado", diskID), http.StatusNotFound)
+		return nil, 0, false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		http.Error(w, "Error leyendo disco: "+err.Error(), http.StatusInternalServerError)
+		return nil, 0, false
+	}
+
+	return file, info.Size(), true
+}
+
+func openPartitionForInspect(w http.ResponseWriter, partitionID string) (*os.File, *Structs.Superblock, bool) {
+	resolved, err := ResolveMountID(partitionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	sb, err := readPartitionSuperblock(resolved.DiskID, &resolved.Record)
+	if err == nil {
+		err = CheckSuperblockLayout(sb, &resolved.Record)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("no se pudo leer el superbloque de %s: %v", partitionID, err), http.StatusConflict)
+		return nil, nil, false
+	}
+
+	file, err := os.OpenFile(resolved.DiskPath, os.O_RDONLY, 0644)
+	if err != nil {
+		http.Error(w, "Error abriendo disco: "+err.Error(), http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	return file, sb, true
+}
+
+func parseIndex(w http.ResponseWriter, value string, count int32, what string) (int32, bool) {
+	index, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || index < 0 || index >= int64(count) {
+		http.Error(w, fmt.Sprintf("%s %s fuera de rango (0-%d)", what, value, count-1), http.StatusBadRequest)
+		return 0, false
+	}
+	return int32(index), true
+}
+
+func rawByte(b byte) string {
+	if b == 0 {
+		return ""
+	}
+	return string(b)
+}
@@ -0,0 +1,193 @@
+package disk
+
+import (
+	Structs "Backend/FileSystem"
+	"Backend/Utils"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	SegmentMBR      = "mbr"
+	SegmentPrimary  = "primary"
+	SegmentExtended = "extended"
+	SegmentEBR      = "ebr"
+	SegmentLogical  = "logical"
+	SegmentFree     = "free"
+)
+
+// LayoutSegment es un tramo del disco en bytes [Start, End). Los tramos de
+// una extendida (EBR, lógicas y huecos) van en Children.
+type LayoutSegment struct {
+	Type     string          `json:"type"`
+	Name     string          `json:"name,omitempty"`
+	Start    int64           `json:"start"`
+	End      int64           `json:"end"`
+	Size     int64           `json:"size"`
+	Percent  float64         `json:"percent"`
+	Fit      string          `json:"fit,omitempty"`
+	Children []LayoutSegment `json:"children,omitempty"`
+}
+
+type DiskLayout struct {
+	DiskID    string          `json:"disk_id"`
+	Size      int64           `json:"size"`
+	Fit       string          `json:"fit"`
+	FreeBytes int64           `json:"free_bytes"`
+	Segments  []LayoutSegment `json:"segments"`
+}
+
+func GetDiskLayout(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	diskID := mux.Vars(r)["diskId"]
+
+	layout, err := ReadDiskLayout(diskID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(layout)
+}
+
+func ReadDiskLayout(diskID string) (*DiskLayout, error) {
+	diskPath := filepath.Join(Utils.GetDiskDirectory(), diskID+".dsk")
+
+	file, err := os.OpenFile(diskPath, os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var mbr Structs.MRB
+	if err := Utils.ReadObject(file, &mbr, 0); err != nil {
+		return nil, err
+	}
+
+	diskSize := info.Size()
+	layout := &DiskLayout{
+		DiskID: diskID,
+		Size:   diskSize,
+		Fit:    convertPartitionFit(mbr.Fit[0]),
+	}
+
+	var segments []LayoutSegment
+	segments = append(segments, newSegment(SegmentMBR, "MBR", 0, int64(binary.Size(mbr)), diskSize))
+
+	var partitions []*Structs.Partition
+	for i := range mbr.Partitions {
+		if mbr.Partitions[i].Size > 0 {
+			partitions = append(partitions, &mbr.Partitions[i])
+		}
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].Start < partitions[j].Start
+	})
+
+	for _, partition := range partitions {
+		start := int64(partition.Start)
+		segment := newSegment(SegmentPrimary, trimName(partition.Name[:]), start, start+int64(partition.Size), diskSize)
+		segment.Fit = convertPartitionFit(partition.Fit[0])
+
+		if partition.Type[0] == 'E' || partition.Type[0] == 'e' {
+			segment.Type = SegmentExtended
+			segment.Children = extendedSegments(file, partition, diskSize)
+		}
+
+		segments = append(segments, segment)
+	}
+
+	layout.Segments = fillGaps(segments, 0, diskSize, diskSize)
+	for _, segment := range layout.Segments {
+		if segment.Type == SegmentFree {
+			layout.FreeBytes += segment.Size
+		}
+	}
+
+	return layout, nil
+}
+
+func extendedSegments(file *os.File, extended *Structs.Partition, diskSize int64) []LayoutSegment {
+	var segments []LayoutSegment
+	ebrSize := int64(binary.Size(Structs.EBR{}))
+
+	WalkEBRChain(file, extended, func(position int32, ebr Structs.EBR) {
+		ebrStart := int64(position)
+		segments = append(segments, newSegment(SegmentEBR, "EBR", ebrStart, ebrStart+ebrSize, diskSize))
+
+		if ebr.PartSize <= 0 {
+			return
+		}
+
+		// Según la implementación, PartStart apunta al EBR o justo después.
+		start := int64(ebr.PartStart)
+		end := start + int64(ebr.PartSize)
+		if start < ebrStart+ebrSize {
+			start = ebrStart + ebrSize
+		}
+
+		logical := newSegment(SegmentLogical, trimName(ebr.PartName[:]), start, end, diskSize)
+		logical.Fit = convertPartitionFit(ebr.PartFit)
+		segments = append(segments, logical)
+	})
+
+	start := int64(extended.Start)
+	return fillGaps(segments, start, start+int64(extended.Size), diskSize)
+}
+
+// fillGaps ordena los tramos y agrega los huecos libres entre from y to.
+func fillGaps(segments []LayoutSegment, from, to, diskSize int64) []LayoutSegment {
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].Start < segments[j].Start
+	})
+
+	result := []LayoutSegment{}
+	cursor := from
+
+	for _, segment := range segments {
+		if segment.Start > cursor {
+			result = append(result, newSegment(SegmentFree, "", cursor, segment.Start, diskSize))
+		}
+		result = append(result, segment)
+		if segment.End > cursor {
+			cursor = segment.End
+		}
+	}
+
+	if cursor < to {
+		result = append(result, newSegment(SegmentFree, "", cursor, to, diskSize))
+	}
+
+	return result
+}
+
+func newSegment(segmentType, name string, start, end, diskSize int64) LayoutSegment {
+	segment := LayoutSegment{
+		Type:  segmentType,
+		Name:  name,
+		Start: start,
+		End:   end,
+		Size:  end - start,
+	}
+	if diskSize > 0 {
+		segment.Percent = float64(segment.Size) * 100 / float64(diskSize)
+	}
+	return segment
+}
+
+func trimName(name []byte) string {
+	return strings.Trim(string(name), "\x00")
+}
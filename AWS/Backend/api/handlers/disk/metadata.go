@@ -0,0 +1,34 @@
+package disk
+
+import (
+	"strings"
+	"time"
+)
+
+// El núcleo guarda las fechas como texto en arreglos de bytes, siempre con
+// este formato.
+const coreTimeLayout = "2006-01-02 15:04"
+
+// FormatCoreTime convierte una fecha del núcleo a RFC3339. Devuelve "" si
+// el campo está vacío y el texto tal cual si no tiene el formato del núcleo.
+func FormatCoreTime(raw []byte) string {
+	t, ok := ParseCoreTime(raw)
+	if !ok {
+		return coreTimeText(raw)
+	}
+	return t.Format(time.RFC3339)
+}
+
+func ParseCoreTime(raw []byte) (time.Time, bool) {
+	text := coreTimeText(raw)
+	if text == "" {
+		return time.Time{}, false
+	}
+
+	t, err := time.ParseInLocation(coreTimeLayout, text, time.Local)
+	return t, err == nil
+}
+
+func coreTimeText(raw []byte) string {
+	return strings.TrimSpace(strings.Trim(string(raw), "\x00"))
+}
@@ -0,0 +1,27 @@
+package disk
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatCoreTime(t *testing.T) {
+	want := time.Date(2024, 3, 9, 14, 5, 0, 0, time.Local).Format(time.RFC3339)
+
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "2024-03-09 14:05\x00\x00", want: want},
+		{raw: "\x00\x00\x00", want: ""},
+		// Otros formatos no se adivinan: 09/03 podría ser marzo o septiembre.
+		{raw: "09/03/2024 14:05", want: "09/03/2024 14:05"},
+		{raw: "2024-03-09 14:05:33", want: "2024-03-09 14:05:33"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatCoreTime([]byte(tt.raw)); got != tt.want {
+			t.Errorf("FormatCoreTime(%q) = %q, se esperaba %q", tt.raw, got, tt.want)
+		}
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"Backend/DiskManagement"
 	Structs "Backend/FileSystem"
 	"Backend/Utils"
+	"Backend/api/handlers/console"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -25,6 +28,19 @@ type PartitionInfo struct {
 	Filesystem string `json:"filesystem"`
 	IsMounted  bool   `json:"is_mounted"`
 	MountID    string `json:"mount_id,omitempty"`
+
+	SizeBytes int64            `json:"size_bytes"`
+	End       int64            `json:"end"`
+	Stats     *FilesystemStats `json:"filesystem_stats,omitempty"`
+
+	LogicalPartitions []PartitionInfo `json:"logical_partitions,omitempty"`
+}
+
+type FilesystemStats struct {
+	InodesCount int32 `json:"inodes_count"`
+	BlocksCount int32 `json:"blocks_count"`
+	FreeInodes  int32 `json:"free_inodes"`
+	FreeBlocks  int32 `json:"free_blocks"`
 }
 
 func GetAllPartitions(w http.ResponseWriter, r *http.Request) {
@@ -34,7 +50,7 @@ func GetAllPartitions(w http.ResponseWriter, r *http.Request) {
 	diskID := vars["diskId"]
 
 	if diskID == "" {
-		fmt.Println("⚠️ [DISK] No se especificó diskId, retornando array vacío")
+		console.Println("⚠️ [DISK] No se especificó diskId, retornando array vacío")
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode([]PartitionInfo{})
 		return
@@ -47,38 +63,46 @@ func GetAllPartitions(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(partitions) == 0 {
-		fmt.Printf("Disco %s no tiene particiones montadas (normal), enviando array vacío\n", diskID)
+		console.Printf("Disco %s no tiene particiones montadas (normal), enviando array vacío\n", diskID)
 	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(partitions)
 }
 
+// ListPartitions devuelve las particiones del disco, vacío si no tiene.
+func ListPartitions(diskID string) []PartitionInfo {
+	partitions := getPartitionsUsingRealStructs(diskID)
+	if partitions == nil {
+		partitions = []PartitionInfo{}
+	}
+	return partitions
+}
+
 func getPartitionsUsingRealStructs(diskID string) []PartitionInfo {
 	var result []PartitionInfo
 
-	fmt.Printf("Verificando si disco %s existe...\n", diskID)
+	console.Printf("Verificando si disco %s existe...\n", diskID)
 
 	testDir := Utils.GetDiskDirectory()
 	diskPath := filepath.Join(testDir, diskID+".dsk")
 
 	if _, err := os.Stat(diskPath); os.IsNotExist(err) {
-		fmt.Printf("Disco %s no existe en %s\n", diskID, diskPath)
+		console.Printf("Disco %s no existe en %s\n", diskID, diskPath)
 		return result
 	}
 
 	mbr := readMBRFromDisk(diskID)
 	if mbr == nil {
-		fmt.Printf("No se pudo leer MBR del disco %s\n", diskID)
+		console.Printf("No se pudo leer MBR del disco %s\n", diskID)
 		return result
 	}
 
-	fmt.Printf("MBR leído correctamente para disco %s\n", diskID)
+	console.Printf("MBR leído correctamente para disco %s\n", diskID)
 
-	mountedPartitions := DiskManagement.GetMountedPartitions()
-	diskMountedParts := mountedPartitions[diskID]
+	diskMountedParts := mountsForDisk(diskPath)
 
-	fmt.Printf("Particiones montadas para disco %s: %d\n", diskID, len(diskMountedParts))
+	console.Printf("Particiones montadas para disco %s: %d\n", diskID, len(diskMountedParts))
 
 	for i := 0; i < 4; i++ {
 		partition := &mbr.Partitions[i]
@@ -90,39 +114,102 @@ func getPartitionsUsingRealStructs(diskID string) []PartitionInfo {
 			}
 
 			partInfo := convertPartitionToInfo(partition, diskID, diskMountedParts)
+			if partition.Type[0] == 'E' || partition.Type[0] == 'e' {
+				partInfo.LogicalPartitions = readLogicalPartitions(diskID, partition, diskMountedParts)
+			}
 			result = append(result, partInfo)
 
-			fmt.Printf("   [DISK] Partición procesada: %s (%s, %s, %s)\n",
+			console.Printf("   [DISK] Partición procesada: %s (%s, %s, %s)\n",
 				partInfo.Name, partInfo.Size, partInfo.Status, partInfo.Filesystem)
 		}
 	}
 
 	if len(result) == 0 {
-		fmt.Printf(" Disco %s existe pero no tiene particiones creadas en el MBR (normal)\n", diskID)
+		console.Printf(" Disco %s existe pero no tiene particiones creadas en el MBR (normal)\n", diskID)
 	}
 
-	fmt.Printf("Resultado final: %d particiones válidas para disco %s\n", len(result), diskID)
+	console.Printf("Resultado final: %d particiones válidas para disco %s\n", len(result), diskID)
 	return result
 }
 
+// readLogicalPartitions convierte cada EBR en una Partition para reutilizar
+// convertPartitionToInfo.
+func readLogicalPartitions(diskID string, extended *Structs.Partition, mountedParts []DiskManagement.MountedPartition) []PartitionInfo {
+	var logicals []PartitionInfo
+
+	diskPath := filepath.Join(Utils.GetDiskDirectory(), diskID+".dsk")
+	file, err := os.OpenFile(diskPath, os.O_RDONLY, 0644)
+	if err != nil {
+		console.Printf("Error abriendo disco %s: %v\n", diskID, err)
+		return logicals
+	}
+	defer file.Close()
+
+	WalkEBRChain(file, extended, func(position int32, ebr Structs.EBR) {
+		name := strings.Trim(string(ebr.PartName[:]), "\x00")
+		if ebr.PartSize <= 0 || name == "" {
+			return
+		}
+
+		logical := Structs.Partition{
+			Status: [1]byte{ebr.PartMount},
+			Type:   [1]byte{'L'},
+			Fit:    [1]byte{ebr.PartFit},
+			Start:  ebr.PartStart,
+			Size:   ebr.PartSize,
+			Name:   ebr.PartName,
+		}
+		logicals = append(logicals, convertPartitionToInfo(&logical, diskID, mountedParts))
+	})
+
+	console.Printf("   [DISK] Extendida %s: %d particiones lógicas\n",
+		strings.Trim(string(extended.Name[:]), "\x00"), len(logicals))
+	return logicals
+}
+
+// WalkEBRChain recorre la lista enlazada de EBR dentro de la extendida. Se
+// detiene al salir de la extendida o si la cadena tiene un ciclo.
+func WalkEBRChain(file *os.File, extended *Structs.Partition, fn func(position int32, ebr Structs.EBR)) {
+	extendedEnd := extended.Start + extended.Size
+	position := extended.Start
+	visited := make(map[int32]bool)
+
+	for position >= extended.Start && position < extendedEnd && !visited[position] {
+		visited[position] = true
+
+		var ebr Structs.EBR
+		if err := Utils.ReadObject(file, &ebr, int64(position)); err != nil {
+			console.Printf("Error leyendo EBR en %d: %v\n", position, err)
+			return
+		}
+
+		fn(position, ebr)
+
+		if ebr.PartNext <= 0 {
+			return
+		}
+		position = ebr.PartNext
+	}
+}
+
 func readMBRFromDisk(diskID string) *Structs.MRB {
 	testDir := Utils.GetDiskDirectory()
 	diskPath := filepath.Join(testDir, diskID+".dsk")
 
 	file, err := os.OpenFile(diskPath, os.O_RDONLY, 0644)
 	if err != nil {
-		fmt.Printf("Error abriendo disco %s: %v\n", diskID, err)
+		console.Printf("Error abriendo disco %s: %v\n", diskID, err)
 		return nil
 	}
 	defer file.Close()
 
 	var mbr Structs.MRB
 	if err := Utils.ReadObject(file, &mbr, 0); err != nil {
-		fmt.Printf("Error leyendo MBR de disco %s: %v\n", diskID, err)
+		console.Printf("Error leyendo MBR de disco %s: %v\n", diskID, err)
 		return nil
 	}
 
-	fmt.Printf(" MBR leído para disco %s\n", diskID)
+	console.Printf(" MBR leído para disco %s\n", diskID)
 	return &mbr
 }
 
@@ -136,6 +223,7 @@ func convertPartitionToInfo(partition *Structs.Partition, diskID string, mounted
 	mountID := ""
 	realPartitionID := ""
 	filesystem := "Sin formatear"
+	var stats *FilesystemStats
 
 	for _, mountedPart := range mountedParts {
 		if strings.Trim(string(mountedPart.Name), "\x00") == name {
@@ -145,6 +233,14 @@ func convertPartitionToInfo(partition *Structs.Partition, diskID string, mounted
 
 			if mountedPart.Status == '1' {
 				filesystem = detectFilesystemFromPartition(diskID, partition)
+				if sb, err := readPartitionSuperblock(diskID, partition); err == nil {
+					stats = &FilesystemStats{
+						InodesCount: sb.S_inodes_count,
+						BlocksCount: sb.S_blocks_count,
+						FreeInodes:  sb.S_free_inodes_count,
+						FreeBlocks:  sb.S_free_blocks_count,
+					}
+				}
 			}
 			break
 		}
@@ -175,6 +271,9 @@ func convertPartitionToInfo(partition *Structs.Partition, diskID string, mounted
 		Filesystem: filesystem,
 		IsMounted:  isMounted,
 		MountID:    mountID,
+		SizeBytes:  int64(partition.Size),
+		End:        int64(partition.Start) + int64(partition.Size),
+		Stats:      stats,
 	}
 }
 
@@ -205,34 +304,48 @@ func convertPartitionFit(fitByte byte) string {
 }
 
 func detectFilesystemFromPartition(diskID string, partition *Structs.Partition) string {
+	superblock, err := readPartitionSuperblock(diskID, partition)
+	switch {
+	case errors.Is(err, errNotFormatted), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "Sin formatear"
+	case err != nil:
+		return "Error leyendo"
+	}
+
+	switch superblock.S_filesystem_type {
+	case 2:
+		return "EXT2"
+	case 3:
+		return "EXT3"
+	default:
+		return "Desconocido"
+	}
+}
+
+var errNotFormatted = errors.New("no está formateada")
+
+// readPartitionSuperblock lee el superbloque al inicio de la partición y
+// falla si no tiene el número mágico de EXT2/EXT3.
+func readPartitionSuperblock(diskID string, partition *Structs.Partition) (*Structs.Superblock, error) {
 	testDir := Utils.GetDiskDirectory()
 	diskPath := filepath.Join(testDir, diskID+".dsk")
 
 	file, err := os.OpenFile(diskPath, os.O_RDONLY, 0644)
 	if err != nil {
-		return "Error leyendo"
+		return nil, err
 	}
 	defer file.Close()
 
 	var superblock Structs.Superblock
-	superblocPos := int64(partition.Start)
-
-	if err := Utils.ReadObject(file, &superblock, superblocPos); err != nil {
-		return "Sin formatear"
+	if err := Utils.ReadObject(file, &superblock, int64(partition.Start)); err != nil {
+		return nil, err
 	}
 
 	if superblock.S_magic != 0xEF53 {
-		return "Sin formatear"
+		return nil, fmt.Errorf("la partición %s %w", strings.Trim(string(partition.Name[:]), "\x00"), errNotFormatted)
 	}
 
-	switch superblock.S_filesystem_type {
-	case 2:
-		return "EXT2"
-	case 3:
-		return "EXT3"
-	default:
-		return "Desconocido"
-	}
+	return &superblock, nil
 }
 
 func formatPartitionSize(bytes int32) string {
@@ -256,18 +369,11 @@ func formatPartitionSize(bytes int32) string {
 }
 
 func GetPartitionDetails(partitionID string) (*PartitionInfo, error) {
-	if len(partitionID) < 2 {
-		return nil, fmt.Errorf("ID de partición inválido: %s", partitionID)
-	}
-
-	diskID := string(partitionID[0])
-	partitions := getPartitionsUsingRealStructs(diskID)
-
-	for _, partition := range partitions {
-		if partition.MountID == partitionID || partition.ID == partitionID {
-			return &partition, nil
-		}
+	resolved, err := ResolveMountID(partitionID)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("partición %s no encontrada", partitionID)
+	info := convertPartitionToInfo(&resolved.Record, resolved.DiskID, mountsForDisk(resolved.DiskPath))
+	return &info, nil
 }
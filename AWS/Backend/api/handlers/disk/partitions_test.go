@@ -0,0 +1,15 @@
+package disk
+
+import (
+	Structs "Backend/FileSystem"
+	"testing"
+)
+
+// Un disco que no se puede abrir no es una partición sin formatear.
+func TestDetectFilesystemUnreadableDisk(t *testing.T) {
+	partition := &Structs.Partition{Start: 0, Size: 1024}
+
+	if got := detectFilesystemFromPartition("zz-disco-que-no-existe", partition); got != "Error leyendo" {
+		t.Errorf("detectFilesystemFromPartition = %q, se esperaba \"Error leyendo\"", got)
+	}
+}
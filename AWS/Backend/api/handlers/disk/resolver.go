@@ -0,0 +1,237 @@
+package disk
+
+import (
+	"Backend/DiskManagement"
+	Structs "Backend/FileSystem"
+	"Backend/Utils"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type PartitionNotFoundError struct {
+	ID string
+}
+
+func (e *PartitionNotFoundError) Error() string {
+	return fmt.Sprintf("partición %s no encontrada", e.ID)
+}
+
+type DiskNotFoundError struct {
+	ID string
+}
+
+func (e *DiskNotFoundError) Error() string {
+	return fmt.Sprintf("disco %s no encontrado", e.ID)
+}
+
+func IsNotFound(err error) bool {
+	var notFound *PartitionNotFoundError
+	var diskNotFound *DiskNotFoundError
+	return errors.As(err, &notFound) || errors.As(err, &diskNotFound)
+}
+
+// ResolvedPartition une el registro de la partición en el disco (MBR o EBR)
+// con su entrada en la tabla de montajes, si está montada.
+type ResolvedPartition struct {
+	MountID  string
+	DiskID   string
+	DiskPath string
+	Name     string
+	Logical  bool
+	Record   Structs.Partition
+	Mount    *DiskManagement.MountedPartition
+}
+
+func (p *ResolvedPartition) Mounted() bool {
+	return p.Mount != nil
+}
+
+func (p *ResolvedPartition) Formatted() bool {
+	return p.Mount != nil && p.Mount.Status == '1'
+}
+
+// ResolveMountID busca una partición por su ID de montaje. Si no está montada
+// se busca el ID guardado en el MBR de los discos.
+func ResolveMountID(mountID string) (*ResolvedPartition, error) {
+	mountID = strings.TrimSpace(mountID)
+	if mountID == "" {
+		return nil, &PartitionNotFoundError{ID: mountID}
+	}
+
+	for _, partitions := range DiskManagement.GetMountedPartitions() {
+		for i := range partitions {
+			mount := partitions[i]
+			if !strings.EqualFold(strings.Trim(string(mount.ID), "\x00"), mountID) {
+				continue
+			}
+
+			resolved, err := resolveOnDisk(mount.Path, strings.Trim(string(mount.Name), "\x00"))
+			if err != nil {
+				return nil, &PartitionNotFoundError{ID: mountID}
+			}
+			resolved.MountID = strings.Trim(string(mount.ID), "\x00")
+			resolved.Mount = &mount
+			return resolved, nil
+		}
+	}
+
+	names, _ := os.ReadDir(Utils.GetDiskDirectory())
+	for _, entry := range names {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".dsk") {
+			continue
+		}
+
+		path := filepath.Join(Utils.GetDiskDirectory(), entry.Name())
+		mbr, err := readMBRFromPath(path)
+		if err != nil {
+			continue
+		}
+
+		for _, partition := range mbr.Partitions {
+			if partition.Size > 0 && strings.EqualFold(strings.Trim(string(partition.Id[:]), "\x00"), mountID) {
+				return resolveOnDisk(path, trimName(partition.Name[:]))
+			}
+		}
+	}
+
+	return nil, &PartitionNotFoundError{ID: mountID}
+}
+
+// ResolveDiskID busca un disco del directorio de discos por su nombre sin
+// ".dsk", sin distinguir mayúsculas, y devuelve el nombre tal como está en el
+// directorio y la ruta. Solo se aceptan archivos del directorio, así que un
+// diskID con "/" o ".." no encuentra nada.
+func ResolveDiskID(diskID string) (string, string, error) {
+	diskID = strings.TrimSpace(diskID)
+	dir := Utils.GetDiskDirectory()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || diskID == "" {
+		return "", "", &DiskNotFoundError{ID: diskID}
+	}
+
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".dsk")
+		if ok && !entry.IsDir() && strings.EqualFold(name, diskID) {
+			return name, filepath.Join(dir, entry.Name()), nil
+		}
+	}
+
+	return "", "", &DiskNotFoundError{ID: diskID}
+}
+
+// ResolvePartitionName busca una partición por disco y nombre.
+func ResolvePartitionName(diskID, name string) (*ResolvedPartition, error) {
+	diskID, path, err := ResolveDiskID(diskID)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := resolveOnDisk(path, name)
+	if err != nil {
+		return nil, &PartitionNotFoundError{ID: diskID + "/" + name}
+	}
+
+	for _, mount := range mountsForDisk(path) {
+		if strings.Trim(string(mount.Name), "\x00") == name {
+			m := mount
+			resolved.MountID = strings.Trim(string(m.ID), "\x00")
+			resolved.Mount = &m
+			break
+		}
+	}
+
+	return resolved, nil
+}
+
+func resolveOnDisk(path, name string) (*ResolvedPartition, error) {
+	mbr, err := readMBRFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := &ResolvedPartition{
+		DiskID:   strings.TrimSuffix(filepath.Base(path), ".dsk"),
+		DiskPath: path,
+		Name:     name,
+	}
+
+	for i := range mbr.Partitions {
+		partition := &mbr.Partitions[i]
+		if partition.Size <= 0 {
+			continue
+		}
+
+		if trimName(partition.Name[:]) == name {
+			resolved.Record = *partition
+			return resolved, nil
+		}
+
+		if partition.Type[0] != 'E' && partition.Type[0] != 'e' {
+			continue
+		}
+
+		file, err := os.OpenFile(path, os.O_RDONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+
+		found := false
+		WalkEBRChain(file, partition, func(position int32, ebr Structs.EBR) {
+			if found || ebr.PartSize <= 0 || trimName(ebr.PartName[:]) != name {
+				return
+			}
+			found = true
+			resolved.Logical = true
+			resolved.Record = Structs.Partition{
+				Status: [1]byte{ebr.PartMount},
+				Type:   [1]byte{'L'},
+				Fit:    [1]byte{ebr.PartFit},
+				Start:  ebr.PartStart,
+				Size:   ebr.PartSize,
+				Name:   ebr.PartName,
+			}
+		})
+		file.Close()
+
+		if found {
+			return resolved, nil
+		}
+	}
+
+	return nil, fmt.Errorf("la partición %s no existe en %s", name, path)
+}
+
+// mountsForDisk devuelve las particiones montadas de un disco comparando la
+// ruta, sin depender de la clave que use la tabla de montajes.
+func mountsForDisk(diskPath string) []DiskManagement.MountedPartition {
+	var mounts []DiskManagement.MountedPartition
+
+	for _, partitions := range DiskManagement.GetMountedPartitions() {
+		for _, mount := range partitions {
+			if filepath.Base(mount.Path) == filepath.Base(diskPath) {
+				mounts = append(mounts, mount)
+			}
+		}
+	}
+
+	return mounts
+}
+
+func readMBRFromPath(path string) (*Structs.MRB, error) {
+	file, err := os.OpenFile(path, os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var mbr Structs.MRB
+	if err := Utils.ReadObject(file, &mbr, 0); err != nil {
+		return nil, err
+	}
+
+	return &mbr, nil
+}
@@ -0,0 +1,14 @@
+package disk
+
+import "testing"
+
+// Un diskID solo nombra archivos .dsk del directorio de discos: rutas y
+// ".." no salen de él.
+func TestResolveDiskIDRejectsPaths(t *testing.T) {
+	for _, id := range []string{"", "../A", "/etc/passwd", "A/../../B", ".."} {
+		name, path, err := ResolveDiskID(id)
+		if err == nil || !IsNotFound(err) {
+			t.Errorf("ResolveDiskID(%q) = %q, %q, %v; se esperaba disco no encontrado", id, name, path, err)
+		}
+	}
+}
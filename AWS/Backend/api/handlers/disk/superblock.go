@@ -0,0 +1,162 @@
+package disk
+
+import (
+	Structs "Backend/FileSystem"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type SuperblockInfo struct {
+	PartitionID string `json:"partition_id"`
+	DiskID      string `json:"disk_id"`
+	Name        string `json:"name"`
+	Filesystem  string `json:"filesystem"`
+
+	FilesystemType int32  `json:"filesystem_type"`
+	InodesCount    int32  `json:"inodes_count"`
+	BlocksCount    int32  `json:"blocks_count"`
+	FreeInodes     int32  `json:"free_inodes_count"`
+	FreeBlocks     int32  `json:"free_blocks_count"`
+	MountTime      string `json:"mount_time"`
+	UnmountTime    string `json:"unmount_time"`
+	MountTimeRaw   string `json:"mount_time_raw"`
+	UnmountTimeRaw string `json:"unmount_time_raw"`
+	MountCount     int32  `json:"mount_count"`
+	Magic          string `json:"magic"`
+	InodeSize      int32  `json:"inode_size"`
+	BlockSize      int32  `json:"block_size"`
+	FirstFreeInode int32  `json:"first_free_inode"`
+	FirstFreeBlock int32  `json:"first_free_block"`
+	BitmapInodes   int32  `json:"bitmap_inode_start"`
+	BitmapBlocks   int32  `json:"bitmap_block_start"`
+	InodeStart     int32  `json:"inode_table_start"`
+	BlockStart     int32  `json:"block_table_start"`
+
+	Usage SuperblockUsage `json:"usage"`
+}
+
+type SuperblockUsage struct {
+	UsedInodes        int32   `json:"used_inodes"`
+	UsedBlocks        int32   `json:"used_blocks"`
+	InodesUsedPercent float64 `json:"inodes_used_percent"`
+	BlocksUsedPercent float64 `json:"blocks_used_percent"`
+	TotalBytes        int64   `json:"total_bytes"`
+	UsedBytes         int64   `json:"used_bytes"`
+	FreeBytes         int64   `json:"free_bytes"`
+}
+
+// GetPartitionSuperblock devuelve el superbloque decodificado de una
+// partición formateada junto con su porcentaje de uso.
+func GetPartitionSuperblock(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	partitionID := mux.Vars(r)["id"]
+
+	info, err := ReadSuperblockInfo(partitionID)
+	if err != nil {
+		if IsNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	json.NewEncoder(w).Encode(info)
+}
+
+func ReadSuperblockInfo(partitionID string) (*SuperblockInfo, error) {
+	resolved, err := ResolveMountID(partitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sb, err := readPartitionSuperblock(resolved.DiskID, &resolved.Record)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer el superbloque de %s: %v", partitionID, err)
+	}
+
+	info := &SuperblockInfo{
+		PartitionID:    resolved.MountID,
+		DiskID:         resolved.DiskID,
+		Name:           resolved.Name,
+		Filesystem:     fmt.Sprintf("EXT%d", sb.S_filesystem_type),
+		FilesystemType: sb.S_filesystem_type,
+		InodesCount:    sb.S_inodes_count,
+		BlocksCount:    sb.S_blocks_count,
+		FreeInodes:     sb.S_free_inodes_count,
+		FreeBlocks:     sb.S_free_blocks_count,
+		MountTime:      FormatCoreTime(sb.S_mtime[:]),
+		UnmountTime:    FormatCoreTime(sb.S_umtime[:]),
+		MountTimeRaw:   strings.Trim(string(sb.S_mtime[:]), "\x00"),
+		UnmountTimeRaw: strings.Trim(string(sb.S_umtime[:]), "\x00"),
+		MountCount:     sb.S_mnt_count,
+		Magic:          fmt.Sprintf("0x%X", sb.S_magic),
+		InodeSize:      sb.S_inode_size,
+		BlockSize:      sb.S_block_size,
+		FirstFreeInode: sb.S_fist_ino,
+		FirstFreeBlock: sb.S_first_blo,
+		BitmapInodes:   sb.S_bm_inode_start,
+		BitmapBlocks:   sb.S_bm_block_start,
+		InodeStart:     sb.S_inode_start,
+		BlockStart:     sb.S_block_start,
+	}
+	if info.PartitionID == "" {
+		info.PartitionID = partitionID
+	}
+
+	usage := &info.Usage
+	usage.UsedInodes = sb.S_inodes_count - sb.S_free_inodes_count
+	usage.UsedBlocks = sb.S_blocks_count - sb.S_free_blocks_count
+	usage.InodesUsedPercent = percent(int64(usage.UsedInodes), int64(sb.S_inodes_count))
+	usage.BlocksUsedPercent = percent(int64(usage.UsedBlocks), int64(sb.S_blocks_count))
+	usage.TotalBytes = int64(sb.S_blocks_count) * int64(sb.S_block_size)
+	usage.UsedBytes = int64(usage.UsedBlocks) * int64(sb.S_block_size)
+	usage.FreeBytes = int64(sb.S_free_blocks_count) * int64(sb.S_block_size)
+
+	return info, nil
+}
+
+func percent(part, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
+
+// CheckSuperblockLayout comprueba que los bitmaps, la tabla de inodos y los
+// bloques que describe el superbloque caben dentro de la partición. Hay que
+// llamarla antes de reservar memoria con los contadores del superbloque,
+// que en una partición dañada pueden tener cualquier valor.
+func CheckSuperblockLayout(sb *Structs.Superblock, partition *Structs.Partition) error {
+	if sb.S_inodes_count <= 0 || sb.S_blocks_count <= 0 || sb.S_inode_size <= 0 || sb.S_block_size <= 0 {
+		return fmt.Errorf("el superbloque indica %d inodos de %d bytes y %d bloques de %d bytes",
+			sb.S_inodes_count, sb.S_inode_size, sb.S_blocks_count, sb.S_block_size)
+	}
+
+	start := int64(partition.Start)
+	end := start + int64(partition.Size)
+
+	areas := []struct {
+		name   string
+		offset int32
+		length int64
+	}{
+		{"bitmap de inodos", sb.S_bm_inode_start, int64(sb.S_inodes_count)},
+		{"bitmap de bloques", sb.S_bm_block_start, int64(sb.S_blocks_count)},
+		{"tabla de inodos", sb.S_inode_start, int64(sb.S_inodes_count) * int64(sb.S_inode_size)},
+		{"bloques", sb.S_block_start, int64(sb.S_blocks_count) * int64(sb.S_block_size)},
+	}
+	for _, area := range areas {
+		offset := int64(area.offset)
+		if offset < start || offset+area.length > end {
+			return fmt.Errorf("%s fuera de la partición: %d bytes desde %d, partición %d-%d", area.name, area.length, offset, start, end)
+		}
+	}
+
+	return nil
+}
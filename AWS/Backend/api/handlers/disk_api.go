@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"Backend/api/handlers/console"
+	"Backend/api/handlers/disk"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type CreateDiskRequest struct {
+	Size int    `json:"size"`
+	Unit string `json:"unit,omitempty"`
+	Fit  string `json:"fit,omitempty"`
+}
+
+type DeleteDiskResponse struct {
+	Success bool           `json:"success"`
+	Message string         `json:"message"`
+	Disk    *disk.DiskInfo `json:"disk"`
+}
+
+// CreateDisk crea un disco ejecutando mkdisk y devuelve el DiskInfo nuevo.
+func CreateDisk(w http.ResponseWriter, r *http.Request) {
+	var req CreateDiskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, APIError{Error: "Request JSON inválido", Code: ErrInvalidParameters})
+		return
+	}
+
+	command := commandLine("mkdisk",
+		"size", strconv.Itoa(req.Size),
+		"unit", strings.ToUpper(req.Unit),
+		"fit", strings.ToUpper(req.Fit),
+	)
+
+	result, ok := runTypedCommand(w, r, command, nil)
+	if !ok {
+		return
+	}
+
+	diskID := strings.TrimSuffix(filepath.Base(result.Outcome.Data.DiskPath), ".dsk")
+	info, err := disk.GetDiskDetails(diskID)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, APIError{Error: err.Error(), Code: ErrInternal, Command: command})
+		return
+	}
+
+	console.Printf("Disco %s creado desde la API\n", diskID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(info)
+}
+
+// DeleteDisk elimina un disco ejecutando rmdisk.
+func DeleteDisk(w http.ResponseWriter, r *http.Request) {
+	diskID, _, err := disk.ResolveDiskID(mux.Vars(r)["diskId"])
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, APIError{Error: err.Error(), Code: ErrNotFound})
+		return
+	}
+
+	info, err := disk.GetDiskDetails(diskID)
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, APIError{Error: err.Error(), Code: ErrNotFound})
+		return
+	}
+
+	if _, ok := runTypedCommand(w, r, commandLine("rmdisk", "driveletter", diskID), nil); !ok {
+		return
+	}
+
+	console.Printf("Disco %s eliminado desde la API\n", diskID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DeleteDiskResponse{
+		Success: true,
+		Message: "Disco " + diskID + " eliminado",
+		Disk:    info,
+	})
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// Los endpoints de discos y particiones resuelven el diskId con el mismo
+// resolver: un id que no es un disco del directorio responde 404 sin tocar
+// archivos fuera de él.
+func TestDiskRoutesResolveDiskID(t *testing.T) {
+	routes := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+	}{
+		{name: "DeleteDisk", handler: DeleteDisk},
+		{name: "CreatePartition", handler: CreatePartition, body: `{"name":"P1","size":1,"unit":"K","type":"P"}`},
+		{name: "DeletePartition", handler: DeletePartition},
+		{name: "ResizePartition", handler: ResizePartition, body: `{"add":1,"unit":"K"}`},
+	}
+
+	for _, route := range routes {
+		for _, id := range []string{"../A", "no-existe"} {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(route.body))
+			r = mux.SetURLVars(r, map[string]string{"diskId": id, "name": "P1"})
+			w := httptest.NewRecorder()
+
+			route.handler(w, r)
+
+			if w.Code != http.StatusNotFound {
+				t.Errorf("%s(%q): status = %d, se esperaba 404: %s", route.name, id, w.Code, w.Body)
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"Backend/DiskManagement"
+	Structs "Backend/FileSystem"
+	"Backend/Utils"
+	"Backend/api/handlers/disk"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type partitionEntry struct {
+	Name  string
+	Type  string
+	Start int32
+	Size  int32
+}
+
+type diskSummary struct {
+	stamp      diskStamp
+	partitions map[string]partitionEntry
+}
+
+// Las funciones de este archivo cambian el estado global del núcleo y
+// requieren coreMu tomado en escritura.
+
+func listDiskFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".dsk") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+func copyDiskFiles(srcDir, dstDir string, names []string) error {
+	for _, name := range names {
+		if err := copyFile(filepath.Join(srcDir, name), filepath.Join(dstDir, name)); err != nil {
+			return fmt.Errorf("no se pudo copiar %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func cloneMountTable(table map[string][]DiskManagement.MountedPartition) map[string][]DiskManagement.MountedPartition {
+	clone := make(map[string][]DiskManagement.MountedPartition, len(table))
+	for disk, partitions := range table {
+		clone[disk] = append([]DiskManagement.MountedPartition(nil), partitions...)
+	}
+	return clone
+}
+
+// replaceMountTable sustituye el contenido de la tabla de montajes del
+// núcleo. GetMountedPartitions devuelve el mapa que usa el propio núcleo, así
+// que se modifica en su lugar; si no fuera así se detecta y se devuelve error
+// para no ejecutar nada sobre los discos reales.
+func replaceMountTable(table map[string][]DiskManagement.MountedPartition) error {
+	current := DiskManagement.GetMountedPartitions()
+	if current == nil {
+		if len(table) == 0 {
+			return nil
+		}
+		return fmt.Errorf("la tabla de montajes del núcleo no está inicializada")
+	}
+
+	for disk := range current {
+		delete(current, disk)
+	}
+	for disk, partitions := range table {
+		current[disk] = append([]DiskManagement.MountedPartition(nil), partitions...)
+	}
+
+	if !sameMountTable(DiskManagement.GetMountedPartitions(), table) {
+		return fmt.Errorf("no se pudo sustituir la tabla de montajes del núcleo")
+	}
+	return nil
+}
+
+func sameMountTable(a, b map[string][]DiskManagement.MountedPartition) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for disk, partitions := range a {
+		other, ok := b[disk]
+		if !ok || len(other) != len(partitions) {
+			return false
+		}
+		for i := range partitions {
+			if partitions[i] != other[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func describeDisks(dir string) map[string]diskSummary {
+	summaries := make(map[string]diskSummary)
+
+	names, err := listDiskFiles(dir)
+	if err != nil {
+		return summaries
+	}
+
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		summaries[name] = diskSummary{
+			stamp:      diskStamp{size: info.Size(), modTime: info.ModTime()},
+			partitions: readPartitionEntries(path),
+		}
+	}
+
+	return summaries
+}
+
+// readPartitionEntries lee las particiones del MBR y las lógicas de la
+// cadena de EBR de la extendida.
+func readPartitionEntries(path string) map[string]partitionEntry {
+	entries := make(map[string]partitionEntry)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return entries
+	}
+	defer file.Close()
+
+	var mbr Structs.MRB
+	if err := Utils.ReadObject(file, &mbr, 0); err != nil {
+		return entries
+	}
+
+	for _, partition := range mbr.Partitions {
+		name := strings.Trim(string(partition.Name[:]), "\x00")
+		if partition.Size <= 0 || name == "" {
+			continue
+		}
+
+		partType := strings.ToUpper(string(partition.Type[:]))
+		entries[name] = partitionEntry{Name: name, Type: partType, Start: partition.Start, Size: partition.Size}
+
+		if partType != "E" {
+			continue
+		}
+
+		disk.WalkEBRChain(file, &partition, func(position int32, ebr Structs.EBR) {
+			logical := strings.Trim(string(ebr.PartName[:]), "\x00")
+			if ebr.PartSize > 0 && logical != "" {
+				entries[logical] = partitionEntry{Name: logical, Type: "L", Start: ebr.PartStart, Size: ebr.PartSize}
+			}
+		})
+	}
+
+	return entries
+}
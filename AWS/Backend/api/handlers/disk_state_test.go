@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	Structs "Backend/FileSystem"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeStruct(t *testing.T, file *os.File, data interface{}, position int64) {
+	t.Helper()
+	if _, err := file.Seek(position, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := binary.Write(file, binary.LittleEndian, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadPartitionEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "A.dsk")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := file.Truncate(8000); err != nil {
+		t.Fatal(err)
+	}
+
+	var mbr Structs.MRB
+	mbr.Partitions[0] = Structs.Partition{Type: [1]byte{'P'}, Start: 1000, Size: 1000}
+	copy(mbr.Partitions[0].Name[:], "P1")
+	mbr.Partitions[1] = Structs.Partition{Type: [1]byte{'e'}, Start: 2000, Size: 3000}
+	copy(mbr.Partitions[1].Name[:], "E1")
+	writeStruct(t, file, &mbr, 0)
+
+	// La segunda lógica apunta de vuelta a la primera: la cadena tiene un
+	// ciclo y la lectura debe terminar igual.
+	first := Structs.EBR{PartStart: 2100, PartSize: 500, PartNext: 2600}
+	copy(first.PartName[:], "L1")
+	second := Structs.EBR{PartStart: 2700, PartSize: 500, PartNext: 2000}
+	copy(second.PartName[:], "L2")
+	writeStruct(t, file, &first, 2000)
+	writeStruct(t, file, &second, 2600)
+
+	got := readPartitionEntries(path)
+	want := map[string]partitionEntry{
+		"P1": {Name: "P1", Type: "P", Start: 1000, Size: 1000},
+		"E1": {Name: "E1", Type: "E", Start: 2000, Size: 3000},
+		"L1": {Name: "L1", Type: "L", Start: 2100, Size: 500},
+		"L2": {Name: "L2", Type: "L", Start: 2700, Size: 500},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("particiones = %+v\nse esperaba    %+v", got, want)
+	}
+}
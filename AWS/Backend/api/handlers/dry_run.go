@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"Backend/DiskManagement"
+	"Backend/Utils"
+	"Backend/api/handlers/console"
+	"Backend/api/handlers/usermanag"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type PartitionChange struct {
+	Disk   string `json:"disk"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Size   int32  `json:"size"`
+	Before int32  `json:"before,omitempty"`
+}
+
+type FileChange struct {
+	PartitionID string `json:"partition_id"`
+	Path        string `json:"path"`
+	Type        string `json:"type"`
+}
+
+type UserChange struct {
+	PartitionID string `json:"partition_id"`
+	Name        string `json:"name"`
+	Group       string `json:"group,omitempty"`
+	// Unverified indica que el comando cambió el disco sin errores pero
+	// el cambio no se pudo comprobar (StatusUnknown).
+	Unverified bool `json:"unverified,omitempty"`
+}
+
+type DryRunChanges struct {
+	DisksCreated      []string          `json:"disks_created"`
+	DisksRemoved      []string          `json:"disks_removed"`
+	DisksModified     []string          `json:"disks_modified"`
+	PartitionsAdded   []PartitionChange `json:"partitions_added"`
+	PartitionsRemoved []PartitionChange `json:"partitions_removed"`
+	PartitionsResized []PartitionChange `json:"partitions_resized"`
+	Mounted           []string          `json:"mounted"`
+	Unmounted         []string          `json:"unmounted"`
+	FilesCreated      []FileChange      `json:"files_created"`
+	UsersCreated      []UserChange      `json:"users_created"`
+	UsersRemoved      []UserChange      `json:"users_removed"`
+	GroupsCreated     []UserChange      `json:"groups_created"`
+	GroupsRemoved     []UserChange      `json:"groups_removed"`
+}
+
+type DryRunResult struct {
+	BatchExecuteResult
+	DryRun  bool          `json:"dry_run"`
+	Changes DryRunChanges `json:"changes"`
+}
+
+// DryRunScript ejecuta un script sobre una copia temporal de los discos y de
+// la tabla de montajes y devuelve lo que cambiaría. Los .dsk reales no se
+// modifican. Solo se simulan comandos que llegan al disco por la tabla de
+// montajes; ver unsimulatedCommands.
+func DryRunScript(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input, err := readScriptInput(r)
+	if err != nil {
+		http.Error(w, err.Error(), scriptErrorStatus(err))
+		return
+	}
+
+	lines, err := lexBatchLines(input.Script)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	policy, err := input.Policy()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if problems := unsimulatedStatements(input.Script); len(problems) > 0 {
+		writeAPIError(w, http.StatusUnprocessableEntity, APIError{
+			Error:       "el script tiene comandos que la simulación no puede redirigir a las copias",
+			Code:        ErrInvalidCommand,
+			Diagnostics: problems,
+		})
+		return
+	}
+
+	result, err := dryRunBatch(r.Context(), usermanag.CallerFromRequest(nil, r), lines, policy)
+	if err != nil {
+		console.Printf("Error en simulación de %s: %v\n", input.Source, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+func dryRunBatch(ctx context.Context, caller *usermanag.Caller, lines []batchLine, policy ErrorPolicy) (*DryRunResult, error) {
+	coreMu.Lock()
+	defer coreMu.Unlock()
+
+	realDir := Utils.GetDiskDirectory()
+
+	scratch, err := os.MkdirTemp("", "mia-dryrun-")
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo crear directorio temporal: %v", err)
+	}
+	defer os.RemoveAll(scratch)
+
+	names, err := listDiskFiles(realDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("no se pudo leer %s: %v", realDir, err)
+	}
+	if err := copyDiskFiles(realDir, scratch, names); err != nil {
+		return nil, err
+	}
+
+	savedMounts := cloneMountTable(DiskManagement.GetMountedPartitions())
+	scratchMounts, err := redirectMountTable(savedMounts, names, scratch)
+	if err != nil {
+		return nil, err
+	}
+
+	// Los cambios se deshacen en orden inverso aunque falle algún paso.
+	defer func() {
+		if err := replaceMountTable(savedMounts); err != nil {
+			console.Printf("Error restaurando tabla de montajes tras simulación: %v\n", err)
+		}
+	}()
+	if err := replaceMountTable(scratchMounts); err != nil {
+		return nil, err
+	}
+
+	sandbox := caller.Fork()
+	defer sandbox.Discard()
+
+	console.Printf("Simulación en %s: %d discos, %d comandos\n", scratch, len(names), len(lines))
+
+	before := describeDisks(scratch)
+
+	batch := runBatch(sandbox, sandboxReportPaths(lines, scratch), batchOptions{
+		ctx:        ctx,
+		policy:     policy,
+		coreLocked: true,
+	})
+
+	changes := diffDiskSummaries(before, describeDisks(scratch))
+	diffMountTables(&changes, scratchMounts, DiskManagement.GetMountedPartitions())
+	collectCommandChanges(&changes, batch.Results)
+
+	return &DryRunResult{
+		BatchExecuteResult: *batch,
+		DryRun:             true,
+		Changes:            changes,
+	}, nil
+}
+
+// unsimulatedCommands buscan el disco en el directorio de discos del núcleo
+// (-driveletter o el siguiente nombre libre). Ese directorio es global: no
+// se puede apuntar a las copias sin afectar a las demás peticiones. execute
+// ejecuta en el núcleo líneas que no pasan por esta revisión.
+var unsimulatedCommands = map[string]bool{
+	"mkdisk":  true,
+	"rmdisk":  true,
+	"fdisk":   true,
+	"mount":   true,
+	"execute": true,
+}
+
+func unsimulatedStatements(script string) []ScriptDiagnostic {
+	statements, _ := LexScript(script)
+
+	var problems []ScriptDiagnostic
+	for _, statement := range statements {
+		name := strings.ToLower(statement.Tokens[0].Text)
+		if !unsimulatedCommands[name] {
+			continue
+		}
+		problems = append(problems, ScriptDiagnostic{
+			Line:     statement.Line,
+			Column:   statement.Column,
+			Severity: SeverityError,
+			Code:     ErrInvalidCommand,
+			Command:  statement.Command,
+			Message:  fmt.Sprintf("%s no se puede simular: usa el directorio de discos real", name),
+		})
+	}
+	return problems
+}
+
+// redirectMountTable apunta las particiones montadas a las copias. Si alguna
+// está en un disco que no se copió, no se puede simular sin tocarlo.
+func redirectMountTable(table map[string][]DiskManagement.MountedPartition, copied []string, dir string) (map[string][]DiskManagement.MountedPartition, error) {
+	available := make(map[string]bool, len(copied))
+	for _, name := range copied {
+		available[name] = true
+	}
+
+	redirected := cloneMountTable(table)
+	for _, partitions := range redirected {
+		for i := range partitions {
+			name := filepath.Base(partitions[i].Path)
+			if !available[name] {
+				return nil, fmt.Errorf("el disco montado %s no está en el directorio de discos", partitions[i].Path)
+			}
+			partitions[i].Path = filepath.Join(dir, name)
+		}
+	}
+
+	return redirected, nil
+}
+
+// Los reportes se generan dentro del directorio temporal.
+func sandboxReportPaths(lines []batchLine, dir string) []batchLine {
+	result := make([]batchLine, len(lines))
+	copy(result, lines)
+
+	for i, line := range result {
+		statement, err := LexCommand(line.Command)
+		if err != nil || len(statement.Tokens) == 0 || strings.ToLower(statement.Tokens[0].Text) != "rep" {
+			continue
+		}
+
+		for j, token := range statement.Tokens {
+			key, value, ok := strings.Cut(token.Text, "=")
+			if ok && strings.ToLower(key) == "-path" {
+				statement.Tokens[j].Text = key + "=" + filepath.Join(dir, "reports", filepath.Base(value))
+			}
+		}
+		result[i].Command = joinTokens(statement.Tokens)
+	}
+
+	return result
+}
+
+func diffDiskSummaries(before, after map[string]diskSummary) DryRunChanges {
+	changes := DryRunChanges{
+		DisksCreated:      []string{},
+		DisksRemoved:      []string{},
+		DisksModified:     []string{},
+		PartitionsAdded:   []PartitionChange{},
+		PartitionsRemoved: []PartitionChange{},
+		PartitionsResized: []PartitionChange{},
+		Mounted:           []string{},
+		Unmounted:         []string{},
+		FilesCreated:      []FileChange{},
+		UsersCreated:      []UserChange{},
+		UsersRemoved:      []UserChange{},
+		GroupsCreated:     []UserChange{},
+		GroupsRemoved:     []UserChange{},
+	}
+
+	for _, name := range sortedDiskNames(after) {
+		summary := after[name]
+		previous, existed := before[name]
+
+		if !existed {
+			changes.DisksCreated = append(changes.DisksCreated, name)
+		} else if previous.stamp != summary.stamp {
+			changes.DisksModified = append(changes.DisksModified, name)
+		}
+
+		for _, partName := range sortedPartitionNames(summary.partitions) {
+			entry := summary.partitions[partName]
+			old, ok := previous.partitions[partName]
+			switch {
+			case !ok:
+				changes.PartitionsAdded = append(changes.PartitionsAdded, PartitionChange{
+					Disk: name, Name: partName, Type: entry.Type, Size: entry.Size,
+				})
+			case old.Size != entry.Size:
+				changes.PartitionsResized = append(changes.PartitionsResized, PartitionChange{
+					Disk: name, Name: partName, Type: entry.Type, Size: entry.Size, Before: old.Size,
+				})
+			}
+		}
+	}
+
+	for _, name := range sortedDiskNames(before) {
+		summary := before[name]
+		current, exists := after[name]
+
+		if !exists {
+			changes.DisksRemoved = append(changes.DisksRemoved, name)
+			continue
+		}
+
+		for _, partName := range sortedPartitionNames(summary.partitions) {
+			entry := summary.partitions[partName]
+			if _, ok := current.partitions[partName]; !ok {
+				changes.PartitionsRemoved = append(changes.PartitionsRemoved, PartitionChange{
+					Disk: name, Name: partName, Type: entry.Type, Size: entry.Size,
+				})
+			}
+		}
+	}
+
+	return changes
+}
+
+func diffMountTables(changes *DryRunChanges, before, after map[string][]DiskManagement.MountedPartition) {
+	ids := func(table map[string][]DiskManagement.MountedPartition) map[string]bool {
+		set := make(map[string]bool)
+		for _, partitions := range table {
+			for _, part := range partitions {
+				set[strings.Trim(string(part.ID), "\x00")] = true
+			}
+		}
+		return set
+	}
+
+	beforeIDs, afterIDs := ids(before), ids(after)
+
+	for id := range afterIDs {
+		if !beforeIDs[id] {
+			changes.Mounted = append(changes.Mounted, id)
+		}
+	}
+	for id := range beforeIDs {
+		if !afterIDs[id] {
+			changes.Unmounted = append(changes.Unmounted, id)
+		}
+	}
+
+	sort.Strings(changes.Mounted)
+	sort.Strings(changes.Unmounted)
+}
+
+// collectCommandChanges toma los archivos, usuarios y grupos de los comandos
+// que se ejecutaron con éxito o sin verificar; no se pueden deducir
+// comparando los discos.
+func collectCommandChanges(changes *DryRunChanges, results []CommandExecutionResult) {
+	for _, res := range results {
+		if !res.Success && res.Status != StatusUnknown {
+			continue
+		}
+		unverified := res.Status == StatusUnknown
+
+		cmd := parseCommandLine(res.Command)
+		partitionID := res.Data.PartitionID
+
+		switch cmd.Name {
+		case "mkdir":
+			changes.FilesCreated = append(changes.FilesCreated, FileChange{PartitionID: partitionID, Path: cmd.Param("path"), Type: "directory"})
+		case "mkfile":
+			changes.FilesCreated = append(changes.FilesCreated, FileChange{PartitionID: partitionID, Path: cmd.Param("path"), Type: "file"})
+		case "mkusr":
+			changes.UsersCreated = append(changes.UsersCreated, UserChange{PartitionID: partitionID, Name: cmd.Param("user"), Group: cmd.Param("grp"), Unverified: unverified})
+		case "rmusr":
+			changes.UsersRemoved = append(changes.UsersRemoved, UserChange{PartitionID: partitionID, Name: cmd.Param("user"), Unverified: unverified})
+		case "mkgrp":
+			changes.GroupsCreated = append(changes.GroupsCreated, UserChange{PartitionID: partitionID, Name: cmd.Param("name"), Unverified: unverified})
+		case "rmgrp":
+			changes.GroupsRemoved = append(changes.GroupsRemoved, UserChange{PartitionID: partitionID, Name: cmd.Param("name"), Unverified: unverified})
+		}
+	}
+}
+
+func sortedDiskNames(summaries map[string]diskSummary) []string {
+	names := make([]string, 0, len(summaries))
+	for name := range summaries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedPartitionNames(entries map[string]partitionEntry) []string {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUnsimulatedStatements(t *testing.T) {
+	script := "mkdisk -size=5\nlogin -user=root -pass=123 -id=A1\n  fdisk -driveletter=A -name=P2 -size=1\nmkdir -path=/docs\nMount -driveletter=A -name=P2"
+
+	problems := unsimulatedStatements(script)
+
+	var lines []int
+	for _, problem := range problems {
+		lines = append(lines, problem.Line)
+		if problem.Severity != SeverityError || problem.Code != ErrInvalidCommand {
+			t.Errorf("diagnóstico = %+v", problem)
+		}
+	}
+	if len(lines) != 3 || lines[0] != 1 || lines[1] != 3 || lines[2] != 5 {
+		t.Fatalf("líneas rechazadas = %v, se esperaba [1 3 5]", lines)
+	}
+	if problems[1].Column != 3 {
+		t.Errorf("columna = %d, se esperaba 3", problems[1].Column)
+	}
+
+	if problems := unsimulatedStatements("login -user=root -pass=123 -id=A1\nmkfs -id=A1\nrep -name=mbr -path=/tmp/a.png -id=A1"); len(problems) != 0 {
+		t.Errorf("se rechazaron comandos simulables: %+v", problems)
+	}
+}
+
+func TestDryRunScriptRejectsDiskDirectoryCommands(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/api/scripts/dry-run", strings.NewReader(`{"script":"mkdisk -size=5 -unit=M"}`))
+	w := httptest.NewRecorder()
+
+	DryRunScript(w, r)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, se esperaba 422: %s", w.Code, w.Body)
+	}
+	var apiErr APIError
+	if err := json.NewDecoder(w.Body).Decode(&apiErr); err != nil {
+		t.Fatal(err)
+	}
+	if len(apiErr.Diagnostics) != 1 || apiErr.Diagnostics[0].Line != 1 {
+		t.Errorf("diagnósticos = %+v", apiErr.Diagnostics)
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type ErrorPolicyMode string
+
+const (
+	PolicyStopOnError   ErrorPolicyMode = "stop"
+	PolicyContinue      ErrorPolicyMode = "continue"
+	PolicyStopOnClasses ErrorPolicyMode = "stop_on"
+)
+
+type ErrorPolicy struct {
+	Mode    ErrorPolicyMode `json:"mode"`
+	Classes []ErrorCode     `json:"classes,omitempty"`
+}
+
+const (
+	StopCompleted = "completed"
+	StopError     = "error"
+	StopCancelled = "cancelled"
+)
+
+// Políticas por defecto: las mismas que tenían execute (solo se detiene
+// ante errores internos) y los comandos separados por ';' (nunca se detiene).
+var (
+	defaultFilePolicy   = ErrorPolicy{Mode: PolicyStopOnClasses, Classes: []ErrorCode{ErrInternal}}
+	defaultStringPolicy = ErrorPolicy{Mode: PolicyContinue}
+)
+
+// Directiva en línea, escrita como comentario al final del comando:
+// "rmdisk -driveletter=A #@onerror=continue".
+var policyDirectivePattern = regexp.MustCompile(`^@onerror=(\w+)$`)
+
+// ParseErrorPolicy arma la política pedida por el cliente; si no pide
+// ninguna se usa fallback.
+func ParseErrorPolicy(mode string, classes []string, fallback ErrorPolicy) (ErrorPolicy, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+
+	if mode == "" && len(classes) == 0 {
+		return fallback, nil
+	}
+
+	if mode == "" {
+		mode = string(PolicyStopOnClasses)
+	}
+
+	policy := ErrorPolicy{Mode: ErrorPolicyMode(mode)}
+
+	switch policy.Mode {
+	case PolicyStopOnError, PolicyContinue:
+		if len(classes) > 0 {
+			return ErrorPolicy{}, fmt.Errorf("la política %s no acepta clases de error", mode)
+		}
+	case PolicyStopOnClasses:
+		if len(classes) == 0 {
+			return ErrorPolicy{}, fmt.Errorf("la política %s requiere al menos una clase de error", mode)
+		}
+		for _, class := range classes {
+			code, ok := lookupErrorCode(class)
+			if !ok {
+				return ErrorPolicy{}, fmt.Errorf("clase de error desconocida: %s", class)
+			}
+			policy.Classes = append(policy.Classes, code)
+		}
+	default:
+		return ErrorPolicy{}, fmt.Errorf("política desconocida: %s (use stop, continue o stop_on)", mode)
+	}
+
+	return policy, nil
+}
+
+func (p ErrorPolicy) ShouldStop(code ErrorCode) bool {
+	switch p.Mode {
+	case PolicyStopOnError:
+		return true
+	case PolicyStopOnClasses:
+		for _, class := range p.Classes {
+			if class == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p ErrorPolicy) String() string {
+	if p.Mode != PolicyStopOnClasses {
+		return string(p.Mode)
+	}
+
+	classes := make([]string, len(p.Classes))
+	for i, class := range p.Classes {
+		classes[i] = string(class)
+	}
+	return fmt.Sprintf("%s[%s]", p.Mode, strings.Join(classes, ","))
+}
+
+func policyDirective(comment string) (ErrorPolicyMode, error) {
+	match := policyDirectivePattern.FindStringSubmatch(strings.TrimSpace(comment))
+	if match == nil {
+		return "", nil
+	}
+
+	mode := ErrorPolicyMode(strings.ToLower(match[1]))
+	if mode != PolicyStopOnError && mode != PolicyContinue {
+		return "", fmt.Errorf("directiva #@onerror inválida: %s (use stop o continue)", mode)
+	}
+
+	return mode, nil
+}
+
+func lookupErrorCode(class string) (ErrorCode, bool) {
+	class = strings.ToUpper(strings.TrimSpace(class))
+	for _, code := range knownErrorCodes {
+		if string(code) == class {
+			return code, true
+		}
+	}
+	return "", false
+}
@@ -1,23 +1,48 @@
 package handlers
 
 import (
-	"Backend/Analyzer"
+	"Backend/api/handlers/console"
+	"Backend/api/handlers/usermanag"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
 type StreamMessage struct {
-	Type      string `json:"type"`
-	Content   string `json:"content"`
-	Timestamp string `json:"timestamp"`
+	Type      string              `json:"type"`
+	Content   string              `json:"content"`
+	Timestamp string              `json:"timestamp"`
+	Line      int                 `json:"line,omitempty"`
+	Command   string              `json:"command,omitempty"`
+	Success   *bool               `json:"success,omitempty"`
+	Status    CommandStatus       `json:"status,omitempty"`
+	Duration  string              `json:"duration,omitempty"`
+	Code      ErrorCode           `json:"code,omitempty"`
+	Summary   *BatchExecuteResult `json:"summary,omitempty"`
 }
 
-func ExecuteWithDirectCommand(w http.ResponseWriter, r *http.Request, command string) {
+func StreamingBatchExecute(w http.ResponseWriter, r *http.Request) {
+	var req CommandRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Command) == "" {
+		http.Error(w, "Request JSON inválido", http.StatusBadRequest)
+		return
+	}
+
+	policy, err := ParseErrorPolicy(req.OnError, req.StopOn, defaultFilePolicy)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ExecuteWithDirectCommand(w, r, strings.TrimSpace(req.Command), policy, req.Transactional)
+}
+
+func ExecuteWithDirectCommand(w http.ResponseWriter, r *http.Request, command string, policy ErrorPolicy, transactional bool) {
 
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -25,46 +50,218 @@ func ExecuteWithDirectCommand(w http.ResponseWriter, r *http.Request, command st
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Accept")
 
-	for key, values := range w.Header() {
-		fmt.Printf("  %s: %s\n", key, strings.Join(values, ", "))
-	}
-
 	if !strings.HasPrefix(strings.ToLower(command), "execute") {
 		sendMessageWithDebug(w, "error", "Solo comandos execute para streaming")
 		return
 	}
 
+	path, hasPath, _ := ParseExecuteCommand(command)
+	if !hasPath {
+		sendMessageWithDebug(w, "error", "El comando execute requiere -path")
+		return
+	}
+
+	client := usermanag.CallerFromRequest(w, r)
+
+	lines, err := readBatchFile(client, path)
+	if err != nil {
+		sendMessageWithDebug(w, "error", err.Error())
+		return
+	}
+
 	sendMessageWithDebug(w, "command", fmt.Sprintf("Ejecutando: %s", command))
 
-	oldStdout := os.Stdout
-	r_pipe, w_pipe, _ := os.Pipe()
-	os.Stdout = w_pipe
+	stream := newEventStream(w)
+	defer stream.close()
+	var current *streamLineWriter
+
+	// Las cabeceras ya se enviaron: un login o logout del script queda en
+	// una copia de la sesión y no cambia la del cliente.
+	caller := client.Fork()
+	defer caller.Discard()
+
+	result := runBatch(caller, lines, batchOptions{
+		ctx:           r.Context(),
+		policy:        policy,
+		transactional: transactional,
+		hooks: batchHooks{
+			onStart: func(line batchLine) {
+				stream.send(StreamMessage{
+					Type:    "command_start",
+					Content: line.Command,
+					Line:    line.Number,
+					Command: line.Command,
+				})
+			},
+			output: func(line batchLine) io.Writer {
+				current = &streamLineWriter{stream: stream, line: line.Number}
+				return current
+			},
+			onFinish: func(res CommandExecutionResult) {
+				current.Flush()
+
+				success := res.Success
+				stream.send(StreamMessage{
+					Type:     "command_end",
+					Content:  res.Message,
+					Line:     res.LineNumber,
+					Command:  res.Command,
+					Success:  &success,
+					Status:   res.Status,
+					Duration: res.ExecutionTime,
+					Code:     res.Code,
+				})
+			},
+		},
+	})
+
+	summary := fmt.Sprintf("%d comandos: %d exitosos, %d fallidos, %d sin verificar en %s",
+		result.TotalCommands, result.SuccessCommands, result.FailedCommands, result.UnknownCommands, result.ExecutionTime)
+	if result.StopDetail != "" {
+		summary += " - detenido: " + result.StopDetail
+	}
+	if result.Transactional {
+		summary += " - transacción: " + result.Transaction
+	}
+
+	stream.send(StreamMessage{
+		Type:    "summary",
+		Content: summary,
+		Summary: result,
+	})
+
+	stream.send(StreamMessage{Type: "complete", Content: "Streaming completado"})
+
+}
+
+// eventStream serializa los eventos SSE de un lote. send solo encola el
+// evento y una goroutine lo escribe: los hooks del lote se llaman con coreMu
+// tomado y un cliente lento no debe frenar a los demás.
+type eventStream struct {
+	mu     sync.Mutex
+	ready  *sync.Cond
+	queue  []StreamMessage
+	closed bool
+	done   chan struct{}
+	w      http.ResponseWriter
+}
+
+func newEventStream(w http.ResponseWriter) *eventStream {
+	s := &eventStream{w: w, done: make(chan struct{})}
+	s.ready = sync.NewCond(&s.mu)
+	go s.writeLoop()
+	return s
+}
+
+func (s *eventStream) send(message StreamMessage) {
+	message.Timestamp = time.Now().Format("15:04:05")
 
-	outputChan := make(chan string, 1)
-	go func() {
-		defer r_pipe.Close()
-		output, _ := io.ReadAll(r_pipe)
-		outputChan <- string(output)
-	}()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	Analyzer.ProcessCommand(command)
+	if s.closed {
+		return
+	}
+	s.queue = append(s.queue, message)
+	s.ready.Signal()
+}
+
+// close espera a que se escriban los eventos encolados; después el handler
+// puede terminar sin que nadie use w.
+func (s *eventStream) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.ready.Signal()
+	s.mu.Unlock()
+
+	<-s.done
+}
+
+func (s *eventStream) writeLoop() {
+	defer close(s.done)
+
+	// Si el cliente se desconecta la cola se sigue vaciando sin escribir.
+	failed := false
+	for {
+		s.mu.Lock()
+		for len(s.queue) == 0 && !s.closed {
+			s.ready.Wait()
+		}
+		pending := s.queue
+		s.queue = nil
+		closed := s.closed
+		s.mu.Unlock()
+
+		for _, message := range pending {
+			if !failed && !s.write(message) {
+				failed = true
+			}
+		}
+
+		if closed && len(pending) == 0 {
+			return
+		}
+	}
+}
+
+func (s *eventStream) write(message StreamMessage) bool {
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		console.Printf("error JSON marshal: %v\n", err)
+		return true
+	}
 
-	w_pipe.Close()
-	os.Stdout = oldStdout
+	if _, err := fmt.Fprintf(s.w, "data: %s\n\n", string(jsonData)); err != nil {
+		return false
+	}
 
-	fullOutput := <-outputChan
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return true
+}
+
+// streamLineWriter envía un evento "output" por cada línea completa que
+// escribe el comando en curso.
+type streamLineWriter struct {
+	stream  *eventStream
+	line    int
+	pending bytes.Buffer
+}
 
-	lines := strings.Split(fullOutput, "\n")
+func (lw *streamLineWriter) Write(p []byte) (int, error) {
+	lw.pending.Write(p)
 
-	for i, line := range lines {
-		if strings.TrimSpace(line) != "" {
-			fmt.Printf("Línea %d: %s\n", i+1, line)
-			sendMessageWithDebug(w, "output", line)
+	for {
+		idx := bytes.IndexByte(lw.pending.Bytes(), '\n')
+		if idx < 0 {
+			break
 		}
+
+		text := string(lw.pending.Next(idx + 1))
+		lw.emit(strings.TrimRight(text, "\r\n"))
 	}
 
-	sendMessageWithDebug(w, "complete", "Streaming completado")
+	return len(p), nil
+}
+
+func (lw *streamLineWriter) Flush() {
+	if lw.pending.Len() > 0 {
+		lw.emit(lw.pending.String())
+		lw.pending.Reset()
+	}
+}
 
+func (lw *streamLineWriter) emit(text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+
+	lw.stream.send(StreamMessage{
+		Type:    "output",
+		Content: text,
+		Line:    lw.line,
+	})
 }
 
 func ExecuteWithRealStreaming(w http.ResponseWriter, r *http.Request) {
@@ -81,22 +278,18 @@ func sendMessageWithDebug(w http.ResponseWriter, msgType, content string) {
 
 	jsonData, err := json.Marshal(message)
 	if err != nil {
-		fmt.Printf("error JSON marshal: %v\n", err)
+		console.Printf("error JSON marshal: %v\n", err)
 		return
 	}
 
 	sseMessage := fmt.Sprintf("data: %s\n\n", string(jsonData))
 
-	n, err := w.Write([]byte(sseMessage))
-	if err != nil {
+	if _, err := w.Write([]byte(sseMessage)); err != nil {
 		return
 	}
-	fmt.Printf(" Escritos %d bytes\n", n)
 
 	if flusher, ok := w.(http.Flusher); ok {
 		flusher.Flush()
-	} else {
-		fmt.Println("No se pudo hacer flush")
 	}
 }
 
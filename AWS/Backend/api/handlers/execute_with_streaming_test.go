@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingWriter no deja escribir hasta que se cierra release, como un
+// cliente que no lee.
+type blockingWriter struct {
+	*httptest.ResponseRecorder
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return w.ResponseRecorder.Write(p)
+}
+
+func TestEventStreamSendDoesNotBlock(t *testing.T) {
+	w := &blockingWriter{ResponseRecorder: httptest.NewRecorder(), release: make(chan struct{})}
+	stream := newEventStream(w)
+
+	sent := make(chan struct{})
+	go func() {
+		for i := 1; i <= 100; i++ {
+			stream.send(StreamMessage{Type: "output", Line: i})
+		}
+		close(sent)
+	}()
+
+	select {
+	case <-sent:
+	case <-time.After(2 * time.Second):
+		t.Fatal("send esperó a que el cliente leyera")
+	}
+
+	close(w.release)
+	stream.close()
+	stream.send(StreamMessage{Type: "output", Line: 101})
+
+	var lines []int
+	for _, event := range strings.Split(strings.TrimSpace(w.Body.String()), "\n\n") {
+		var message StreamMessage
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(event, "data: ")), &message); err != nil {
+			t.Fatalf("evento inválido %q: %v", event, err)
+		}
+		lines = append(lines, message.Line)
+	}
+
+	if len(lines) != 100 {
+		t.Fatalf("se escribieron %d eventos, se esperaban 100", len(lines))
+	}
+	for i, line := range lines {
+		if line != i+1 {
+			t.Fatalf("evento %d con línea %d: se perdió el orden", i, line)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"Backend/api/handlers/console"
+	"Backend/api/handlers/disk"
+	"Backend/api/handlers/filemanag"
+	"Backend/api/handlers/usermanag"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// uploadFormOverhead es el margen sobre filemanag.MaxFileSize que se
+// admite en el cuerpo para las cabeceras del formulario multipart.
+const uploadFormOverhead = 64 << 10
+
+// UploadFile escribe un archivo en la partición de la sesión. El contenido
+// se guarda en un temporal y se crea con mkfile -cont, así el dueño, el
+// grupo y los permisos los asigna el núcleo igual que en la consola.
+// Acepta multipart (campo "file") o el cuerpo crudo; ?parents=true equivale
+// a mkfile -r.
+//
+// Si el archivo ya existe se reemplaza: el nuevo se crea con un nombre
+// temporal y después se borra el original y se renombra, así un fallo de
+// mkfile deja el original intacto. Reemplazar requiere permiso de escritura
+// sobre el original y el archivo nuevo queda a nombre de quien lo sube.
+//
+// El cuerpo se recibe antes de tomar coreMu en escritura para que un
+// cliente lento no detenga al resto.
+func UploadFile(w http.ResponseWriter, r *http.Request) {
+	filePath, ok := cleanPartitionPath(w, r.URL.Query().Get("path"))
+	if !ok {
+		return
+	}
+
+	maxBody := filemanag.MaxFileSize + uploadFormOverhead
+	if r.ContentLength > maxBody {
+		writeFileTooLarge(w)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+
+	parents := r.URL.Query().Get("parents") == "true"
+	content, err := uploadedContent(r)
+	if err != nil {
+		if bodyTooLarge(err) {
+			writeFileTooLarge(w)
+			return
+		}
+		writeAPIError(w, http.StatusBadRequest, APIError{Error: err.Error(), Code: ErrInvalidParameters})
+		return
+	}
+	defer content.Close()
+	if r.MultipartForm != nil && r.PostFormValue("parents") == "true" {
+		parents = true
+	}
+
+	staged, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, APIError{Error: err.Error(), Code: ErrInternal})
+		return
+	}
+	defer os.Remove(staged.Name())
+
+	size, err := io.Copy(staged, io.LimitReader(content, filemanag.MaxFileSize+1))
+	staged.Close()
+	if err != nil {
+		if bodyTooLarge(err) {
+			writeFileTooLarge(w)
+			return
+		}
+		writeAPIError(w, http.StatusBadRequest, APIError{Error: "error recibiendo el archivo: " + err.Error(), Code: ErrInvalidParameters})
+		return
+	}
+	if size > filemanag.MaxFileSize {
+		writeFileTooLarge(w)
+		return
+	}
+
+	coreMu.Lock()
+	defer coreMu.Unlock()
+
+	resolved, session, _, ok := requireSessionPartition(w, r, filePath)
+	if !ok {
+		return
+	}
+
+	existing, err := filemanag.StatPath(resolved, filePath)
+	replacing := err == nil
+	if replacing && existing.Type == "directory" {
+		writeAPIError(w, http.StatusConflict, APIError{Error: fmt.Sprintf("%s es una carpeta", filePath), Code: ErrTargetExists})
+		return
+	}
+
+	target := filePath
+	if replacing {
+		// El reemplazo se hace con FSWriter; si no puede abrirse (por
+		// ejemplo en EXT3) se avisa antes de crear nada.
+		probe, err := filemanag.OpenFSWriter(resolved, session)
+		if err != nil {
+			code := fsErrorCode(err)
+			writeAPIError(w, httpStatusForCode(code), APIError{Error: err.Error(), Code: code})
+			return
+		}
+		probe.Close()
+
+		target = path.Join(path.Dir(filePath), uploadTempName())
+	}
+
+	command := commandLine("mkfile", "path", target, "cont", staged.Name())
+	if parents {
+		command += " -r"
+	}
+
+	explain := func() (ErrorCode, string, bool) {
+		return explainCreateInPartition(resolved, target, parents)
+	}
+	if _, ok := runTypedCommand(w, r, command, explain); !ok {
+		return
+	}
+
+	if replacing && !replaceWithUpload(w, session, resolved, target, filePath) {
+		return
+	}
+
+	item, err := filemanag.StatPath(resolved, filePath)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, APIError{Error: err.Error(), Code: ErrInternal, Command: command})
+		return
+	}
+
+	status := http.StatusCreated
+	if replacing {
+		status = http.StatusOK
+		console.Printf("Archivo %s reemplazado en %s (%d bytes)\n", filePath, resolved.MountID, size)
+	} else {
+		console.Printf("Archivo %s subido a %s (%d bytes)\n", filePath, resolved.MountID, size)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(item)
+}
+
+// replaceWithUpload cambia el archivo filePath por upload, recién creado en
+// la misma carpeta. Si falla, upload se borra y el original se conserva.
+func replaceWithUpload(w http.ResponseWriter, session *usermanag.SessionInfo, resolved *disk.ResolvedPartition, upload, filePath string) bool {
+	replaced := runFSWrite(w, session, resolved, func(fs *filemanag.FSWriter) error {
+		if err := fs.Remove(filePath, false); err != nil {
+			return err
+		}
+		_, err := fs.Rename(upload, path.Base(filePath))
+		return err
+	})
+	if replaced {
+		return true
+	}
+
+	fs, err := filemanag.OpenFSWriter(resolved, session)
+	if err == nil {
+		defer fs.Close()
+		if err = fs.Remove(upload, false); err == nil {
+			err = fs.Commit()
+		}
+	}
+	if err != nil {
+		console.Printf("No se pudo borrar la subida temporal %s: %v\n", upload, err)
+	}
+	return false
+}
+
+// uploadTempName cabe en B_name (12 bytes) con su terminador.
+func uploadTempName() string {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("no se pudo generar nombre temporal: %v", err))
+	}
+	return ".up" + hex.EncodeToString(buf)
+}
+
+func writeFileTooLarge(w http.ResponseWriter) {
+	writeAPIError(w, http.StatusRequestEntityTooLarge, APIError{
+		Error: fmt.Sprintf("el archivo supera el máximo que admite un inodo (%d bytes)", filemanag.MaxFileSize),
+		Code:  ErrNoSpace,
+	})
+}
+
+func bodyTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
+
+// requireSessionPartition valida la ruta y que la partición sea la de la
+// sesión, que es donde el núcleo escribe. Requiere coreMu tomado.
+func requireSessionPartition(w http.ResponseWriter, r *http.Request, rawPath string) (*disk.ResolvedPartition, *usermanag.SessionInfo, string, bool) {
+	partitionID := mux.Vars(r)["partitionId"]
+
+	filePath, ok := cleanPartitionPath(w, rawPath)
+	if !ok {
+		return nil, nil, "", false
+	}
+
+	session, ok := usermanag.SessionFromRequest(r)
+	if !ok {
+		writeAPIError(w, http.StatusUnauthorized, APIError{Error: "no hay sesión activa", Code: ErrNoSession})
+		return nil, nil, "", false
+	}
+
+	resolved, err := disk.ResolveMountID(partitionID)
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, APIError{Error: err.Error(), Code: ErrNotFound})
+		return nil, nil, "", false
+	}
+
+	if !resolved.Formatted() {
+		writeAPIError(w, http.StatusConflict, APIError{Error: fmt.Sprintf("la partición %s no está montada o formateada", partitionID), Code: ErrNotMounted})
+		return nil, nil, "", false
+	}
+
+	if !strings.EqualFold(resolved.MountID, session.PartitionID) {
+		writeAPIError(w, http.StatusForbidden, APIError{
+			Error: fmt.Sprintf("solo se puede modificar la partición de la sesión (%s)", session.PartitionID),
+			Code:  ErrPermissionDenied,
+		})
+		return nil, nil, "", false
+	}
+
+	return resolved, session, filePath, true
+}
+
+func cleanPartitionPath(w http.ResponseWriter, raw string) (string, bool) {
+	if !strings.HasPrefix(raw, "/") {
+		writeAPIError(w, http.StatusBadRequest, APIError{Error: "path debe ser una ruta absoluta", Code: ErrInvalidParameters})
+		return "", false
+	}
+
+	cleaned := path.Clean(raw)
+	if cleaned == "/" {
+		writeAPIError(w, http.StatusBadRequest, APIError{Error: "path no puede ser la raíz", Code: ErrInvalidParameters})
+		return "", false
+	}
+	return cleaned, true
+}
+
+func uploadedContent(r *http.Request) (io.ReadCloser, error) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return r.Body, nil
+	}
+
+	if err := r.ParseMultipartForm(filemanag.MaxFileSize); err != nil {
+		return nil, fmt.Errorf("formulario inválido: %w", err)
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, errors.New("falta el campo file")
+	}
+	return file, nil
+}
+
+// explainCreateInPartition busca por qué el núcleo no creó la ruta.
+func explainCreateInPartition(resolved *disk.ResolvedPartition, filePath string, parents bool) (ErrorCode, string, bool) {
+	parent := path.Dir(filePath)
+	if parent != "/" && !parents {
+		if _, err := filemanag.StatPath(resolved, parent); err != nil {
+			return ErrNotFound, fmt.Sprintf("no existe la carpeta %s (usa parents=true para crearla)", parent), true
+		}
+	}
+
+	if info, err := disk.ReadSuperblockInfo(resolved.MountID); err == nil {
+		if info.FreeInodes == 0 {
+			return ErrNoFreeInodes, "no quedan inodos libres en la partición", true
+		}
+		if info.FreeBlocks == 0 {
+			return ErrNoFreeBlocks, "no quedan bloques libres en la partición", true
+		}
+	}
+
+	return "", "", false
+}
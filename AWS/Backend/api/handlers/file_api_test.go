@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"Backend/api/handlers/filemanag"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUploadFileTooLarge(t *testing.T) {
+	if want := int64((12 + 16 + 256 + 4096) * 64); filemanag.MaxFileSize != want {
+		t.Fatalf("MaxFileSize = %d, se esperaba %d", filemanag.MaxFileSize, want)
+	}
+
+	content := bytes.Repeat([]byte{'x'}, int(filemanag.MaxFileSize)+1)
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, err := writer.CreateFormFile("file", "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	tests := []struct {
+		name          string
+		body          []byte
+		contentType   string
+		contentLength int64
+	}{
+		{name: "Content-Length declarado", body: content, contentLength: int64(len(content))},
+		{name: "sin Content-Length", body: content, contentLength: -1},
+		{name: "multipart", body: form.Bytes(), contentType: writer.FormDataContentType(), contentLength: int64(form.Len())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/api/files/A1?path=/a.txt", bytes.NewReader(tt.body))
+			r.ContentLength = tt.contentLength
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+
+			UploadFile(w, r)
+
+			if w.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("status = %d, se esperaba 413: %s", w.Code, w.Body)
+			}
+			var apiErr APIError
+			if err := json.NewDecoder(w.Body).Decode(&apiErr); err != nil {
+				t.Fatal(err)
+			}
+			if apiErr.Code != ErrNoSpace || !strings.Contains(apiErr.Error, "máximo") {
+				t.Errorf("error = %+v", apiErr)
+			}
+		})
+	}
+}
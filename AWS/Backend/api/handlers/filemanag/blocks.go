@@ -0,0 +1,122 @@
+package filemanag
+
+import (
+	Structs "Backend/FileSystem"
+	"Backend/Utils"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// I_block tiene 12 apuntadores directos y luego el indirecto simple (12),
+// doble (13) y triple (14). Cada bloque de apuntadores guarda 16 entradas.
+const directBlocks = 12
+
+var pointersPerBlock = int64(len(Structs.Pointerblock{}.B_pointers))
+
+// MaxFileSize es lo más que puede direccionar un inodo, con los tres
+// niveles de indirectos llenos.
+var MaxFileSize = (directBlocks + pointersPerBlock + pointersPerBlock*pointersPerBlock + pointersPerBlock*pointersPerBlock*pointersPerBlock) * fileChunk
+
+var (
+	// SkipBlock devuelto para un bloque de apuntadores evita descender en él.
+	SkipBlock = errors.New("omitir bloque")
+	// StopWalk detiene el recorrido sin error.
+	StopWalk = errors.New("detener recorrido")
+)
+
+type BlockRef struct {
+	Block   int32
+	Level   int
+	Pointer bool
+}
+
+// WalkInodeBlocks llama a fn con cada bloque del inodo en orden lógico,
+// incluidos los bloques de apuntadores (Pointer=true, Level 1-3). Los
+// bloques fuera de rango se informan pero no se leen. Un bloque de
+// apuntadores ilegible no detiene el recorrido; se devuelve el primer error.
+func WalkInodeBlocks(file *os.File, sb *Structs.Superblock, inode *Structs.Inode, fn func(BlockRef) error) error {
+	var firstErr error
+
+	var walk func(block int32, level int) error
+	walk = func(block int32, level int) error {
+		err := fn(BlockRef{Block: block, Level: level, Pointer: level > 0})
+		if err != nil || level == 0 {
+			return err
+		}
+		if block < 0 || block >= sb.S_blocks_count {
+			return nil
+		}
+
+		var pointers Structs.Pointerblock
+		if err := Utils.ReadObject(file, &pointers, blockPosition(sb, block)); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("error leyendo bloque de apuntadores %d: %v", block, err)
+			}
+			return nil
+		}
+
+		for _, pointer := range pointers.B_pointers {
+			if pointer == -1 {
+				continue
+			}
+			if err := walk(pointer, level-1); err != nil && err != SkipBlock {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i, block := range inode.I_block {
+		if block == -1 {
+			continue
+		}
+
+		level := 0
+		if i >= directBlocks {
+			level = i - directBlocks + 1
+		}
+
+		if err := walk(block, level); err != nil && err != SkipBlock {
+			if err == StopWalk {
+				return firstErr
+			}
+			return err
+		}
+	}
+
+	return firstErr
+}
+
+// DataBlocks devuelve los bloques de datos del inodo en orden, sin los de
+// apuntadores ni los que están fuera de rango.
+func DataBlocks(file *os.File, sb *Structs.Superblock, inode *Structs.Inode) ([]int32, error) {
+	var blocks []int32
+	err := WalkInodeBlocks(file, sb, inode, func(ref BlockRef) error {
+		if !ref.Pointer && ref.Block >= 0 && ref.Block < sb.S_blocks_count {
+			blocks = append(blocks, ref.Block)
+		}
+		return nil
+	})
+	return blocks, err
+}
+
+// walkFolderBlocks recorre los bloques de carpeta de un directorio. fn
+// recibe el número de bloque y su contenido; StopWalk termina el recorrido.
+func walkFolderBlocks(file *os.File, sb *Structs.Superblock, dirInode *Structs.Inode, fn func(block int32, folder *Structs.Folderblock) error) error {
+	return WalkInodeBlocks(file, sb, dirInode, func(ref BlockRef) error {
+		if ref.Pointer || ref.Block < 0 || ref.Block >= sb.S_blocks_count {
+			return nil
+		}
+
+		var folderBlock Structs.Folderblock
+		if err := Utils.ReadObject(file, &folderBlock, blockPosition(sb, ref.Block)); err != nil {
+			return nil
+		}
+		return fn(ref.Block, &folderBlock)
+	})
+}
+
+func blockPosition(sb *Structs.Superblock, block int32) int64 {
+	return int64(sb.S_block_start) + int64(block)*int64(sb.S_block_size)
+}
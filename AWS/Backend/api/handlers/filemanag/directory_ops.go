@@ -82,35 +82,14 @@ func ReadDirectoryContents(file *os.File, sb *Structs.Superblock, dirInodeIndex
 		return nil, fmt.Errorf("no es un directorio")
 	}
 
-	hasBlocks := false
-	for i := 0; i < 12; i++ {
-		if dirInode.I_block[i] != -1 {
-			hasBlocks = true
-			break
-		}
-	}
-
-	if !hasBlocks {
-		return contents, nil 
-	}
-
-	for i := 0; i < 12; i++ {
-		if dirInode.I_block[i] == -1 {
-			continue 
-		}
-
-		var folderBlock Structs.Folderblock
-		blockPos := int64(sb.S_block_start + dirInode.I_block[i]*sb.S_block_size)
-		if err := Utils.ReadObject(file, &folderBlock, blockPos); err != nil {
-			continue 
-		}
-
+	walkFolderBlocks(file, sb, &dirInode, func(block int32, folderBlock *Structs.Folderblock) error {
 		for j := 0; j < 4; j++ {
 			if folderBlock.B_content[j].B_inodo != -1 {
 				contents = append(contents, folderBlock.B_content[j])
 			}
 		}
-	}
+		return nil
+	})
 
 	return contents, nil 
 }
@@ -160,17 +139,8 @@ func FindFileInDirectory(file *os.File, sb *Structs.Superblock, dirInodeIndex in
 		return -1, fmt.Errorf("no es un directorio")
 	}
 
-	for i := 0; i < 12; i++ {
-		if dirInode.I_block[i] == -1 {
-			break
-		}
-
-		var folderBlock Structs.Folderblock
-		blockPos := int64(sb.S_block_start + dirInode.I_block[i]*sb.S_block_size)
-		if err := Utils.ReadObject(file, &folderBlock, blockPos); err != nil {
-			continue
-		}
-
+	found := int32(-1)
+	walkFolderBlocks(file, sb, &dirInode, func(block int32, folderBlock *Structs.Folderblock) error {
 		for j := 0; j < 4; j++ {
 			if folderBlock.B_content[j].B_inodo == -1 {
 				continue
@@ -178,9 +148,15 @@ func FindFileInDirectory(file *os.File, sb *Structs.Superblock, dirInodeIndex in
 
 			name := strings.Trim(string(folderBlock.B_content[j].B_name[:]), "\x00")
 			if name == fileName {
-				return folderBlock.B_content[j].B_inodo, nil
+				found = folderBlock.B_content[j].B_inodo
+				return StopWalk
 			}
 		}
+		return nil
+	})
+
+	if found != -1 {
+		return found, nil
 	}
 
 	return -1, fmt.Errorf("archivo '%s' no encontrado", fileName)
@@ -1,12 +1,10 @@
 package filemanag
 
 import (
-	"Backend/FileManagement"
 	Structs "Backend/FileSystem"
-	"Backend/UserManagement"
 	"Backend/Utils"
+	"Backend/api/handlers/disk"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -68,92 +66,18 @@ func GetFileInfoFromInode(file *os.File, sb *Structs.Superblock, inodeIndex int3
 		OwnerUID:    ownerUID,
 		GroupGID:    groupGID,
 		FullPath:    fullPath,
+		SizeBytes:   int64(inode.I_size),
+		Inode:       inodeIndex,
+		Atime:       disk.FormatCoreTime(inode.I_atime[:]),
+		Ctime:       disk.FormatCoreTime(inode.I_ctime[:]),
+		Mtime:       disk.FormatCoreTime(inode.I_mtime[:]),
 	}
-	return result, nil
-}
-
-func VerifyIsFile(filePath string) (bool, error) {
-	partition, file, err := UserManagement.FindMountedPartitionForLoggedUser(UserManagement.CurrentSession.PartitionID)
-	if err != nil {
-		return false, fmt.Errorf("error accediendo partición: %v", err)
-	}
-	defer file.Close()
-
-	sb, err := UserManagement.ReadSuperblock(file, partition)
-	if err != nil {
-		return false, fmt.Errorf("error leyendo superbloque: %v", err)
-	}
-
-	dir, fileName := filepath.Split(filePath)
-	dir = strings.TrimSuffix(dir, "/")
-	if dir == "" {
-		dir = "/"
-	}
-
-	dirInodeIndex, err := FindDirectoryInode(file, sb, dir)
-	if err != nil {
-		return false, fmt.Errorf("directorio padre no encontrado: %v", err)
-	}
-
-	fileInodeIndex, err := FindFileInDirectory(file, sb, dirInodeIndex, fileName)
-	if err != nil {
-		return false, fmt.Errorf("archivo no encontrado: %v", err)
-	}
-
-	var inode Structs.Inode
-	inodePos := int64(sb.S_inode_start + fileInodeIndex*sb.S_inode_size)
-	if err := Utils.ReadObject(file, &inode, inodePos); err != nil {
-		return false, fmt.Errorf("error leyendo inodo: %v", err)
-	}
-
-	isFile := inode.I_type[0] == '1'
-
-	return isFile, nil
-}
-
-func GetFileContentDirect(filePath string) (string, error) {
-	files := map[string]string{
-		"file1": filePath,
-	}
-
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	outputChan := make(chan string, 1)
-	go func() {
-		defer r.Close()
-		output, _ := io.ReadAll(r)
-		outputChan <- string(output)
-	}()
-
-	FileManagement.Cat(files)
-
-	w.Close()
-	os.Stdout = oldStdout
-
-	output := <-outputChan
-
-	return ExtractContentFromCatOutput(output), nil
-}
-
-func ExtractContentFromCatOutput(output string) string {
-	lines := strings.Split(output, "\n")
-	var contentLines []string
-	inContent := false
-
-	for _, line := range lines {
-		if strings.Contains(line, "========================Start CAT========================") {
-			inContent = true
-			continue
-		}
-		if strings.Contains(line, "========================End CAT========================") {
-			break
+	// Blocks cuenta solo bloques de datos, como el tamaño del archivo.
+	WalkInodeBlocks(file, sb, &inode, func(ref BlockRef) error {
+		if !ref.Pointer {
+			result.Blocks++
 		}
-		if inContent && !strings.HasPrefix(line, "==========") {
-			contentLines = append(contentLines, line)
-		}
-	}
-
-	return strings.Join(contentLines, "\n")
+		return nil
+	})
+	return result, nil
 }
@@ -0,0 +1,231 @@
+package filemanag
+
+import (
+	Structs "Backend/FileSystem"
+	"Backend/Utils"
+	"Backend/api/handlers/disk"
+	"Backend/api/handlers/usermanag"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrFileNotFound     = errors.New("archivo no encontrado")
+	ErrIsDirectory      = errors.New("la ruta es un directorio")
+	ErrPermissionDenied = errors.New("permiso denegado")
+)
+
+// fileChunk es lo que cabe de contenido en un bloque de archivo.
+var fileChunk = int64(binary.Size(Structs.Fileblock{}))
+
+// InodeReader lee el contenido de un archivo directo de sus bloques, sin
+// pasar por cat. Implementa io.ReaderAt y respeta I_size.
+type InodeReader struct {
+	file   *os.File
+	sb     *Structs.Superblock
+	Index  int32
+	Inode  Structs.Inode
+	blocks []int32
+}
+
+func NewInodeReader(file *os.File, sb *Structs.Superblock, index int32) (*InodeReader, error) {
+	var inode Structs.Inode
+	if err := Utils.ReadObject(file, &inode, int64(sb.S_inode_start)+int64(index)*int64(sb.S_inode_size)); err != nil {
+		return nil, err
+	}
+
+	if inode.I_type[0] == '0' {
+		return nil, ErrIsDirectory
+	}
+	if inode.I_type[0] != '1' {
+		return nil, fmt.Errorf("el inodo %d no es un archivo", index)
+	}
+
+	blocks, err := DataBlocks(file, sb, &inode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InodeReader{file: file, sb: sb, Index: index, Inode: inode, blocks: blocks}, nil
+}
+
+func (r *InodeReader) Size() int64 {
+	return int64(r.Inode.I_size)
+}
+
+func (r *InodeReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("offset negativo: %d", off)
+	}
+
+	size := r.Size()
+	n := 0
+	for n < len(p) && off < size {
+		index := off / fileChunk
+		if index >= int64(len(r.blocks)) {
+			return n, io.ErrUnexpectedEOF
+		}
+
+		within := off % fileChunk
+		count := fileChunk - within
+		if remaining := int64(len(p) - n); count > remaining {
+			count = remaining
+		}
+		if left := size - off; count > left {
+			count = left
+		}
+
+		position := blockPosition(r.sb, r.blocks[index]) + within
+		read, err := r.file.ReadAt(p[n:n+int(count)], position)
+		n += read
+		off += int64(read)
+		if err != nil {
+			return n, err
+		}
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// ReadRange lee length bytes desde offset; length < 0 lee hasta el final.
+func (r *InodeReader) ReadRange(offset, length int64) ([]byte, error) {
+	size := r.Size()
+	if offset < 0 || offset > size {
+		return nil, fmt.Errorf("offset %d fuera del archivo (%d bytes)", offset, size)
+	}
+	if length < 0 || length > size-offset {
+		length = size - offset
+	}
+
+	data := make([]byte, length)
+	n, err := r.ReadAt(data, offset)
+	if err != nil && err != io.EOF {
+		return data[:n], err
+	}
+	return data[:n], nil
+}
+
+// openedFile es un archivo de una partición abierto para lectura directa.
+type openedFile struct {
+	disk   *os.File
+	sb     *Structs.Superblock
+	reader *InodeReader
+	path   string
+	name   string
+}
+
+func (f *openedFile) Close() error {
+	return f.disk.Close()
+}
+
+// openPartitionFile resuelve la ruta dentro de la partición y valida que
+// el usuario de la sesión pueda leer el archivo.
+func openPartitionFile(resolved *disk.ResolvedPartition, filePath string, session *usermanag.SessionInfo) (*openedFile, error) {
+	file, sb, err := openPartitionDisk(resolved, os.O_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := FindDirectoryInode(file, sb, filePath)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%w: %s", ErrFileNotFound, filePath)
+	}
+
+	reader, err := NewInodeReader(file, sb, index)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if !canRead(&reader.Inode, session) {
+		file.Close()
+		return nil, fmt.Errorf("%w: %s no puede leer %s", ErrPermissionDenied, session.Username, filePath)
+	}
+
+	trimmed := strings.TrimRight(filePath, "/")
+	return &openedFile{
+		disk:   file,
+		sb:     sb,
+		reader: reader,
+		path:   filePath,
+		name:   trimmed[strings.LastIndex(trimmed, "/")+1:],
+	}, nil
+}
+
+// openPartitionDisk abre el disco de la partición y lee su superbloque.
+func openPartitionDisk(resolved *disk.ResolvedPartition, flag int) (*os.File, *Structs.Superblock, error) {
+	file, err := os.OpenFile(resolved.DiskPath, flag, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var sb Structs.Superblock
+	if err := Utils.ReadObject(file, &sb, int64(resolved.Record.Start)); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if sb.S_magic != 0xEF53 {
+		file.Close()
+		return nil, nil, fmt.Errorf("la partición %s no está formateada", resolved.Name)
+	}
+
+	return file, &sb, nil
+}
+
+// StatPath devuelve la información de un archivo o carpeta de la partición
+// sin pasar por la sesión del núcleo.
+func StatPath(resolved *disk.ResolvedPartition, filePath string) (*FileSystemItem, error) {
+	file, sb, err := openPartitionDisk(resolved, os.O_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	index, err := FindDirectoryInode(file, sb, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrFileNotFound, filePath)
+	}
+
+	parent, name := path.Split(strings.TrimRight(filePath, "/"))
+	if name == "" {
+		return GetFileInfoFromInode(file, sb, index, "", "/")
+	}
+	return GetFileInfoFromInode(file, sb, index, name, parent)
+}
+
+// canRead aplica los permisos UGO del inodo; root puede leer todo.
+func canRead(inode *Structs.Inode, session *usermanag.SessionInfo) bool {
+	return hasPermission(inode, session, permRead)
+}
+
+func hasPermission(inode *Structs.Inode, session *usermanag.SessionInfo, bit int) bool {
+	if session.IsRoot {
+		return true
+	}
+
+	perm := strings.Trim(string(inode.I_perm[:]), "\x00")
+	if len(perm) != 3 {
+		return false
+	}
+
+	digit := perm[2]
+	switch {
+	case int(inode.I_uid) == session.UID:
+		digit = perm[0]
+	case int(inode.I_gid) == session.GID:
+		digit = perm[1]
+	}
+
+	value, err := strconv.Atoi(string(digit))
+	return err == nil && value&bit != 0
+}
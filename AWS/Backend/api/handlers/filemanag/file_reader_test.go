@@ -0,0 +1,107 @@
+package filemanag
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestInodeReaderReadRange(t *testing.T) {
+	// 1000 bytes ocupan 16 bloques: 12 directos y 4 por el indirecto simple.
+	content := testContent(1000)
+
+	img := newTestImage(t, 8, 32)
+	index := img.mkfile(0, "a.txt", content)
+	resolved := img.finish()
+
+	file, sb, err := openPartitionDisk(resolved, os.O_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	reader, err := NewInodeReader(file, sb, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reader.Size() != 1000 {
+		t.Fatalf("Size = %d, se esperaba 1000", reader.Size())
+	}
+
+	tests := []struct {
+		name    string
+		offset  int64
+		length  int64
+		want    []byte
+		wantErr bool
+	}{
+		{name: "todo", offset: 0, length: -1, want: content},
+		{name: "inicio", offset: 0, length: 10, want: content[:10]},
+		{name: "entre dos bloques", offset: 60, length: 10, want: content[60:70]},
+		{name: "en el indirecto", offset: 800, length: 100, want: content[800:900]},
+		{name: "recortado al final", offset: 990, length: 100, want: content[990:]},
+		{name: "justo al final", offset: 1000, length: -1, want: []byte{}},
+		{name: "largo máximo", offset: 1, length: math.MaxInt64, want: content[1:]},
+		{name: "offset pasado el final", offset: 1001, length: 1, wantErr: true},
+		{name: "offset negativo", offset: -1, length: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reader.ReadRange(tt.offset, tt.length)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("se esperaba error, se leyeron %d bytes", len(got))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("ReadRange(%d, %d) = %q, se esperaba %q", tt.offset, tt.length, got, tt.want)
+			}
+		})
+	}
+
+	buf := make([]byte, 20)
+	if n, err := reader.ReadAt(buf, 990); n != 10 || err != io.EOF {
+		t.Errorf("ReadAt al final = %d, %v; se esperaba 10, EOF", n, err)
+	}
+}
+
+func TestNewInodeReaderDirectory(t *testing.T) {
+	img := newTestImage(t, 4, 8)
+	dir := img.mkdir(0, "docs")
+	resolved := img.finish()
+
+	file, sb, err := openPartitionDisk(resolved, os.O_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if _, err := NewInodeReader(file, sb, dir); !errors.Is(err, ErrIsDirectory) {
+		t.Errorf("NewInodeReader en un directorio = %v, se esperaba ErrIsDirectory", err)
+	}
+}
+
+// Blocks cuenta bloques de datos: el bloque de apuntadores del indirecto
+// simple no suma.
+func TestGetFileInfoFromInodeCountsDataBlocks(t *testing.T) {
+	img := newTestImage(t, 4, 32)
+	content := testContent(int(img.sb.S_block_size)*(directBlocks+1) - 5)
+	index := img.mkfile(0, "grande.txt", content)
+	defer img.finish()
+
+	item, err := GetFileInfoFromInode(img.file, &img.sb, index, "grande.txt", "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Blocks != directBlocks+1 {
+		t.Errorf("Blocks = %d, se esperaba %d", item.Blocks, directBlocks+1)
+	}
+}
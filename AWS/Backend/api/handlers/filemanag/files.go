@@ -1,12 +1,21 @@
 package filemanag
 
 import (
-	"Backend/DiskManagement"
 	"Backend/UserManagement"
+	"Backend/api/handlers/console"
+	"Backend/api/handlers/disk"
+	"Backend/api/handlers/usermanag"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
 )
@@ -22,6 +31,12 @@ type FileSystemItem struct {
 	OwnerUID    string `json:"owner_uid"`
 	GroupGID    string `json:"group_gid"`
 	FullPath    string `json:"full_path"`
+	SizeBytes   int64  `json:"size_bytes"`
+	Inode       int32  `json:"inode"`
+	Blocks      int    `json:"blocks"`
+	Atime       string `json:"atime,omitempty"`
+	Ctime       string `json:"ctime,omitempty"`
+	Mtime       string `json:"mtime,omitempty"`
 }
 
 func GetAllFiles(w http.ResponseWriter, r *http.Request) {
@@ -36,7 +51,7 @@ func GetAllFiles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !UserManagement.IsLoggedIn() {
+	if _, ok := usermanag.SessionFromRequest(r); !ok {
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode([]FileSystemItem{})
 		return
@@ -48,13 +63,19 @@ func GetAllFiles(w http.ResponseWriter, r *http.Request) {
 	// }
 
 	// COMO NO HAY RESTRICCIÓN DE VISTA PARA EL ID DE LA PARTICIÓN QUE SE INGRESA SE PUEDE VER EL DE LOS DEMÁS
-	if !isPartitionMountedAndFormatted(partitionID) {
+	resolved, err := disk.ResolveMountID(partitionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if !resolved.Formatted() {
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode([]FileSystemItem{})
 		return
 	}
 
-	files, err := getFilesFromAnyPartition(partitionID, r.URL.Query().Get("path"))
+	files, err := getFilesFromAnyPartition(r, resolved.MountID, r.URL.Query().Get("path"))
 	if err != nil {
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode([]FileSystemItem{})
@@ -69,38 +90,18 @@ func GetAllFiles(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(files)
 }
 
-func isPartitionMountedAndFormatted(partitionID string) bool {
-	mountedPartitions := DiskManagement.GetMountedPartitions()
-
-	for _, partitions := range mountedPartitions {
-		for _, part := range partitions {
-			cleanID := strings.Trim(string(part.ID), "\x00")
-			if cleanID == partitionID {
-				if part.Status == '1' {
-					return true
-				} else {
-					return false
-				}
-			}
-		}
-	}
-
-	return false
-}
-
-func getFilesFromAnyPartition(partitionID, path string) ([]FileSystemItem, error) {
+func getFilesFromAnyPartition(r *http.Request, partitionID, path string) ([]FileSystemItem, error) {
 	if path == "" {
 		path = "/"
 	}
 
-	originalSession := UserManagement.CurrentSession
-
-	UserManagement.CurrentSession.PartitionID = partitionID
-
-	files, err := GetFilesFromDirectory(path)
-
-	UserManagement.CurrentSession = originalSession
+	var files []FileSystemItem
+	var err error
 
+	usermanag.WithSession(r, func() {
+		UserManagement.CurrentSession.PartitionID = partitionID
+		files, err = GetFilesFromDirectory(path)
+	})
 
 	return files, err
 }
@@ -109,62 +110,168 @@ func GetFileContent(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	vars := mux.Vars(r)
-	partitionID := vars["partitionId"]
-	filePath := r.URL.Query().Get("path")
-
-	if partitionID == "" || filePath == "" {
-		http.Error(w, "Partición y path requeridos", http.StatusBadRequest)
+	opened, session, ok := openRequestedFile(w, r)
+	if !ok {
 		return
 	}
+	defer opened.Close()
 
-	if !UserManagement.IsLoggedIn() {
-		http.Error(w, "Se requiere sesión activa", http.StatusUnauthorized)
+	offset, length, err := parseByteRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if !isPartitionMountedAndFormatted(partitionID) {
-		http.Error(w, fmt.Sprintf("Partición %s no está montada o formateada", partitionID), http.StatusBadRequest)
+	if offset > opened.reader.Size() {
+		http.Error(w, fmt.Sprintf("offset %d fuera del archivo (%d bytes)", offset, opened.reader.Size()), http.StatusRequestedRangeNotSatisfiable)
 		return
 	}
 
-	content, err := getFileContentFromAnyPartition(partitionID, filePath)
+	data, err := opened.reader.ReadRange(offset, length)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error leyendo archivo: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	// El contenido binario se manda en base64 para no alterarlo en el JSON.
+	content, encoding := string(data), "utf-8"
+	if !utf8.Valid(data) {
+		content, encoding = base64.StdEncoding.EncodeToString(data), "base64"
+	}
+
 	response := map[string]interface{}{
 		"content":   content,
-		"path":      filePath,
-		"partition": partitionID,
-		"read_by":   UserManagement.CurrentSession.Username,
+		"encoding":  encoding,
+		"path":      opened.path,
+		"partition": mux.Vars(r)["partitionId"],
+		"size":      opened.reader.Size(),
+		"offset":    offset,
+		"length":    len(data),
+		"read_by":   session.Username,
 		"mode":      "universal_explorer",
 	}
 
 	json.NewEncoder(w).Encode(response)
 }
 
-func getFileContentFromAnyPartition(partitionID, filePath string) (string, error) {
+// GetFileRaw devuelve el contenido tal cual, con soporte de Range.
+func GetFileRaw(w http.ResponseWriter, r *http.Request) {
+	opened, _, ok := openRequestedFile(w, r)
+	if !ok {
+		return
+	}
+	defer opened.Close()
+
+	modified, _ := disk.ParseCoreTime(opened.reader.Inode.I_mtime[:])
+	http.ServeContent(w, r, opened.name, modified, io.NewSectionReader(opened.reader, 0, opened.reader.Size()))
+}
+
+// DownloadFile manda el archivo como adjunto. El tipo se detecta por el
+// contenido y el ETag sale del inodo, así que cambia al modificarse.
+func DownloadFile(w http.ResponseWriter, r *http.Request) {
+	opened, _, ok := openRequestedFile(w, r)
+	if !ok {
+		return
+	}
+	defer opened.Close()
+
+	inode := &opened.reader.Inode
+	modified, _ := disk.ParseCoreTime(inode.I_mtime[:])
 
-	originalSession := UserManagement.CurrentSession
+	head := make([]byte, 512)
+	n, _ := opened.reader.ReadAt(head, 0)
 
-	UserManagement.CurrentSession.PartitionID = partitionID
+	w.Header().Set("Content-Type", detectContentType(opened.name, head[:n]))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": opened.name}))
+	w.Header().Set("ETag", fmt.Sprintf(`"%d-%d-%x"`, opened.reader.Index, inode.I_size, strings.Trim(string(inode.I_mtime[:]), "\x00")))
 
-	isFile, err := VerifyIsFile(filePath)
+	console.Printf("Descarga de %s desde %s\n", opened.path, mux.Vars(r)["partitionId"])
+	http.ServeContent(w, r, opened.name, modified, io.NewSectionReader(opened.reader, 0, opened.reader.Size()))
+}
+
+// detectContentType usa el contenido y, si no es concluyente, la extensión.
+func detectContentType(name string, head []byte) string {
+	detected := http.DetectContentType(head)
+	if detected != "application/octet-stream" {
+		return detected
+	}
+
+	if byExtension := mime.TypeByExtension(filepath.Ext(name)); byExtension != "" {
+		return byExtension
+	}
+	return detected
+}
+
+// openRequestedFile valida la sesión, la partición y la ruta de la petición
+// y abre el archivo. Si algo falla escribe el error y devuelve false.
+func openRequestedFile(w http.ResponseWriter, r *http.Request) (*openedFile, *usermanag.SessionInfo, bool) {
+	partitionID := mux.Vars(r)["partitionId"]
+	filePath := r.URL.Query().Get("path")
+
+	if partitionID == "" || filePath == "" {
+		http.Error(w, "Partición y path requeridos", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	session, ok := usermanag.SessionFromRequest(r)
+	if !ok {
+		http.Error(w, "Se requiere sesión activa", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+
+	resolved, err := disk.ResolveMountID(partitionID)
 	if err != nil {
-		UserManagement.CurrentSession = originalSession
-		return "", err
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	if !resolved.Formatted() {
+		http.Error(w, fmt.Sprintf("Partición %s no está montada o formateada", partitionID), http.StatusBadRequest)
+		return nil, nil, false
 	}
 
-	if !isFile {
-		UserManagement.CurrentSession = originalSession
-		return "", fmt.Errorf("'%s' es un directorio, no un archivo", filePath)
+	opened, err := openPartitionFile(resolved, filePath, session)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error leyendo archivo: %v", err), fileErrorStatus(err))
+		return nil, nil, false
 	}
 
-	content, err := GetFileContentDirect(filePath)
+	return opened, session, true
+}
 
-	UserManagement.CurrentSession = originalSession
+func fileErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrFileNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrIsDirectory):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrPermissionDenied):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// parseByteRange lee ?offset= y ?length=; sin length se lee hasta el final.
+func parseByteRange(r *http.Request) (int64, int64, error) {
+	query := r.URL.Query()
+	offset, length := int64(0), int64(-1)
+
+	if value := query.Get("offset"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			return 0, 0, fmt.Errorf("offset inválido: %s", value)
+		}
+		offset = parsed
+	}
+
+	if value := query.Get("length"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			return 0, 0, fmt.Errorf("length inválido: %s", value)
+		}
+		length = parsed
+	}
 
-	return content, err
+	return offset, length, nil
 }
@@ -0,0 +1,828 @@
+package filemanag
+
+import (
+	Structs "Backend/FileSystem"
+	"Backend/Utils"
+	"Backend/api/handlers/disk"
+	"Backend/api/handlers/usermanag"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// El núcleo no tiene comandos para renombrar, mover, copiar ni borrar, así
+// que FSWriter modifica las estructuras directamente. Los bitmaps y los
+// contadores del superbloque se cambian en memoria y solo se escriben en
+// Commit. Cada operación primero valida permisos, nombres y destinos, lee
+// lo que necesita y reserva y escribe lo nuevo en inodos y bloques que nada
+// apunta todavía; la escritura que lo enlaza (la entrada de carpeta, o el
+// apuntador y el inodo si hizo falta un bloque de carpeta nuevo) va al final.
+// Si algo falla antes, la partición no cambia. Un error de E/S después del
+// enlace sí puede dejarla inconsistente; fsck lo detecta.
+//
+// Nada de esto pasa por el journal, así que las particiones EXT3 se
+// rechazan.
+
+var (
+	ErrTargetExists  = errors.New("el destino ya existe")
+	ErrNotDirectory  = errors.New("no es un directorio")
+	ErrNotEmpty      = errors.New("el directorio no está vacío")
+	ErrInvalidName   = errors.New("nombre inválido")
+	ErrInvalidTarget = errors.New("destino inválido")
+	ErrNoFreeInodes  = errors.New("no quedan inodos libres")
+	ErrNoFreeBlocks  = errors.New("no quedan bloques libres")
+	ErrJournaled     = errors.New("la partición es EXT3 y la operación no quedaría en el journal")
+	ErrProtected     = errors.New("archivo protegido")
+)
+
+// usersFile lo mantienen mkusr, mkgrp y compañía; borrarlo o cambiarlo de
+// sitio deja la partición sin usuarios.
+const usersFile = "/users.txt"
+
+const (
+	permRead  = 4
+	permWrite = 2
+)
+
+type FSWriter struct {
+	file        *os.File
+	sb          *Structs.Superblock
+	sbPos       int64
+	session     *usermanag.SessionInfo
+	inodeBitmap []byte
+	blockBitmap []byte
+	inodeStyle  [2]byte
+	blockStyle  [2]byte
+}
+
+type dirEntry struct {
+	block   int32
+	slot    int
+	content Structs.Content
+}
+
+func (e *dirEntry) Name() string {
+	return strings.Trim(string(e.content.B_name[:]), "\x00")
+}
+
+func OpenFSWriter(resolved *disk.ResolvedPartition, session *usermanag.SessionInfo) (*FSWriter, error) {
+	file, sb, err := openPartitionDisk(resolved, os.O_RDWR)
+	if err != nil {
+		return nil, err
+	}
+	if sb.S_filesystem_type == 3 {
+		file.Close()
+		return nil, fmt.Errorf("%w: %s", ErrJournaled, resolved.MountID)
+	}
+	if err := disk.CheckSuperblockLayout(sb, &resolved.Record); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	fs := &FSWriter{
+		file:        file,
+		sb:          sb,
+		sbPos:       int64(resolved.Record.Start),
+		session:     session,
+		inodeBitmap: make([]byte, sb.S_inodes_count),
+		blockBitmap: make([]byte, sb.S_blocks_count),
+	}
+
+	if _, err := file.ReadAt(fs.inodeBitmap, int64(sb.S_bm_inode_start)); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error leyendo bitmap de inodos: %v", err)
+	}
+	if _, err := file.ReadAt(fs.blockBitmap, int64(sb.S_bm_block_start)); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error leyendo bitmap de bloques: %v", err)
+	}
+
+	fs.inodeStyle = [2]byte{disk.BitmapValue(fs.inodeBitmap, false), disk.BitmapValue(fs.inodeBitmap, true)}
+	fs.blockStyle = [2]byte{disk.BitmapValue(fs.blockBitmap, false), disk.BitmapValue(fs.blockBitmap, true)}
+	return fs, nil
+}
+
+func (fs *FSWriter) Close() error {
+	return fs.file.Close()
+}
+
+// Commit escribe los bitmaps y actualiza los contadores del superbloque.
+func (fs *FSWriter) Commit() error {
+	if _, err := fs.file.WriteAt(fs.inodeBitmap, int64(fs.sb.S_bm_inode_start)); err != nil {
+		return err
+	}
+	if _, err := fs.file.WriteAt(fs.blockBitmap, int64(fs.sb.S_bm_block_start)); err != nil {
+		return err
+	}
+
+	fs.sb.S_free_inodes_count, fs.sb.S_fist_ino = countFree(fs.inodeBitmap)
+	fs.sb.S_free_blocks_count, fs.sb.S_first_blo = countFree(fs.blockBitmap)
+	return Utils.WriteObject(fs.file, fs.sb, fs.sbPos)
+}
+
+// Remove borra un archivo o carpeta. Una carpeta con contenido solo se
+// borra con recursive.
+func (fs *FSWriter) Remove(target string, recursive bool) error {
+	if err := checkProtected(target); err != nil {
+		return err
+	}
+
+	parentIndex, parent, entry, err := fs.lookupEntry(target)
+	if err != nil {
+		return err
+	}
+	if !fs.allowed(parent, permWrite) {
+		return fmt.Errorf("%w: no se puede escribir en %s", ErrPermissionDenied, path.Dir(target))
+	}
+
+	index := entry.content.B_inodo
+	inode, err := fs.readInode(index)
+	if err != nil {
+		return err
+	}
+
+	if inode.I_type[0] == '0' && !recursive {
+		children, err := fs.children(inode)
+		if err != nil {
+			return err
+		}
+		if len(children) > 0 {
+			return fmt.Errorf("%w: %s", ErrNotEmpty, target)
+		}
+	}
+
+	if err := fs.checkTree(target, inode, permWrite); err != nil {
+		return err
+	}
+	if err := fs.freeTree(index, inode); err != nil {
+		return err
+	}
+	if err := fs.writeEntry(entry.block, entry.slot, Structs.Content{B_inodo: -1}); err != nil {
+		return err
+	}
+
+	return fs.touch(parentIndex, parent)
+}
+
+// Rename cambia el nombre dentro de la misma carpeta y devuelve la ruta nueva.
+func (fs *FSWriter) Rename(target, name string) (string, error) {
+	if err := validName(name); err != nil {
+		return "", err
+	}
+	if err := checkProtected(target); err != nil {
+		return "", err
+	}
+
+	parentIndex, parent, entry, err := fs.lookupEntry(target)
+	if err != nil {
+		return "", err
+	}
+	if !fs.allowed(parent, permWrite) {
+		return "", fmt.Errorf("%w: no se puede escribir en %s", ErrPermissionDenied, path.Dir(target))
+	}
+	if _, exists, err := fs.findEntry(parent, name); err != nil {
+		return "", err
+	} else if exists {
+		return "", fmt.Errorf("%w: %s", ErrTargetExists, path.Join(path.Dir(target), name))
+	}
+
+	content := entry.content
+	content.B_name = [12]byte{}
+	copy(content.B_name[:], name)
+	if err := fs.writeEntry(entry.block, entry.slot, content); err != nil {
+		return "", err
+	}
+
+	return path.Join(path.Dir(target), name), fs.touch(parentIndex, parent)
+}
+
+// Move mueve un archivo o carpeta dentro de la carpeta destination y
+// devuelve la ruta nueva.
+func (fs *FSWriter) Move(target, destination string) (string, error) {
+	if err := checkProtected(target); err != nil {
+		return "", err
+	}
+
+	parentIndex, parent, entry, err := fs.lookupEntry(target)
+	if err != nil {
+		return "", err
+	}
+	destIndex, dest, err := fs.lookupDir(destination)
+	if err != nil {
+		return "", err
+	}
+
+	if destination == target || strings.HasPrefix(destination, target+"/") {
+		return "", fmt.Errorf("%w: no se puede mover %s dentro de sí mismo", ErrInvalidTarget, target)
+	}
+	if !fs.allowed(parent, permWrite) {
+		return "", fmt.Errorf("%w: no se puede escribir en %s", ErrPermissionDenied, path.Dir(target))
+	}
+	if !fs.allowed(dest, permWrite) {
+		return "", fmt.Errorf("%w: no se puede escribir en %s", ErrPermissionDenied, destination)
+	}
+
+	if parentIndex == destIndex {
+		return target, nil
+	}
+
+	name := entry.Name()
+	if _, exists, err := fs.findEntry(dest, name); err != nil {
+		return "", err
+	} else if exists {
+		return "", fmt.Errorf("%w: %s", ErrTargetExists, path.Join(destination, name))
+	}
+
+	index := entry.content.B_inodo
+	inode, err := fs.readInode(index)
+	if err != nil {
+		return "", err
+	}
+
+	var dotDot *dirEntry
+	if inode.I_type[0] == '0' {
+		if dotDot, _, err = fs.findEntry(inode, ".."); err != nil {
+			return "", err
+		}
+	}
+
+	link, err := fs.prepareEntry(destIndex, dest, name, index)
+	if err != nil {
+		return "", err
+	}
+	if err := link(); err != nil {
+		return "", err
+	}
+
+	if err := fs.writeEntry(entry.block, entry.slot, Structs.Content{B_inodo: -1}); err != nil {
+		return "", err
+	}
+	if dotDot != nil {
+		dotDot.content.B_inodo = destIndex
+		if err := fs.writeEntry(dotDot.block, dotDot.slot, dotDot.content); err != nil {
+			return "", err
+		}
+	}
+
+	if err := fs.touch(parentIndex, parent); err != nil {
+		return "", err
+	}
+	return path.Join(destination, name), fs.touch(destIndex, dest)
+}
+
+// Copy copia un archivo, o una carpeta con recursive, dentro de la carpeta
+// destination. La copia queda a nombre del usuario de la sesión.
+func (fs *FSWriter) Copy(target, destination string, recursive bool) (string, error) {
+	_, _, entry, err := fs.lookupEntry(target)
+	if err != nil {
+		return "", err
+	}
+	destIndex, dest, err := fs.lookupDir(destination)
+	if err != nil {
+		return "", err
+	}
+
+	index := entry.content.B_inodo
+	inode, err := fs.readInode(index)
+	if err != nil {
+		return "", err
+	}
+
+	if inode.I_type[0] == '0' {
+		if !recursive {
+			return "", fmt.Errorf("%w: %s es una carpeta, usa recursive", ErrIsDirectory, target)
+		}
+		if destination == target || strings.HasPrefix(destination, target+"/") {
+			return "", fmt.Errorf("%w: no se puede copiar %s dentro de sí mismo", ErrInvalidTarget, target)
+		}
+	}
+	if !fs.allowed(dest, permWrite) {
+		return "", fmt.Errorf("%w: no se puede escribir en %s", ErrPermissionDenied, destination)
+	}
+
+	name := entry.Name()
+	if _, exists, err := fs.findEntry(dest, name); err != nil {
+		return "", err
+	} else if exists {
+		return "", fmt.Errorf("%w: %s", ErrTargetExists, path.Join(destination, name))
+	}
+
+	if err := fs.checkTree(target, inode, permRead); err != nil {
+		return "", err
+	}
+
+	copied, err := fs.copyTree(index, inode, destIndex)
+	if err != nil {
+		return "", err
+	}
+	link, err := fs.prepareEntry(destIndex, dest, name, copied)
+	if err != nil {
+		return "", err
+	}
+	if err := link(); err != nil {
+		return "", err
+	}
+
+	return path.Join(destination, name), fs.touch(destIndex, dest)
+}
+
+func (fs *FSWriter) copyTree(index int32, source *Structs.Inode, parent int32) (int32, error) {
+	copiedIndex, err := fs.allocInode()
+	if err != nil {
+		return -1, err
+	}
+
+	copied := *source
+	copied.I_uid = int32(fs.session.UID)
+	copied.I_gid = int32(fs.session.GID)
+	now := coreNow()
+	copied.I_atime, copied.I_ctime, copied.I_mtime = now, now, now
+	for i := range copied.I_block {
+		copied.I_block[i] = -1
+	}
+
+	if source.I_type[0] != '0' {
+		if err := fs.copyContent(index, &copied); err != nil {
+			return -1, err
+		}
+		return copiedIndex, fs.writeInode(copiedIndex, &copied)
+	}
+
+	if err := fs.initDirectory(copiedIndex, &copied, parent); err != nil {
+		return -1, err
+	}
+
+	children, err := fs.children(source)
+	if err != nil {
+		return -1, err
+	}
+	for _, child := range children {
+		childInode, err := fs.readInode(child.content.B_inodo)
+		if err != nil {
+			return -1, err
+		}
+		childCopy, err := fs.copyTree(child.content.B_inodo, childInode, copiedIndex)
+		if err != nil {
+			return -1, err
+		}
+		if err := fs.addEntry(copiedIndex, &copied, child.Name(), childCopy); err != nil {
+			return -1, err
+		}
+	}
+
+	return copiedIndex, nil
+}
+
+func (fs *FSWriter) copyContent(index int32, copied *Structs.Inode) error {
+	reader, err := NewInodeReader(fs.file, fs.sb, index)
+	if err != nil {
+		return err
+	}
+
+	for offset := int64(0); offset < reader.Size(); offset += fileChunk {
+		var block Structs.Fileblock
+		if _, err := reader.ReadAt(block.B_content[:], offset); err != nil && err != io.EOF {
+			return err
+		}
+
+		number, err := fs.allocBlock()
+		if err != nil {
+			return err
+		}
+		if err := Utils.WriteObject(fs.file, &block, blockPosition(fs.sb, number)); err != nil {
+			return err
+		}
+		if err := fs.attachNew(copied, number); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// initDirectory crea el primer bloque de carpeta con "." y "..".
+func (fs *FSWriter) initDirectory(index int32, dir *Structs.Inode, parent int32) error {
+	folder := emptyFolder()
+	copy(folder.B_content[0].B_name[:], ".")
+	folder.B_content[0].B_inodo = index
+	copy(folder.B_content[1].B_name[:], "..")
+	folder.B_content[1].B_inodo = parent
+
+	number, err := fs.allocBlock()
+	if err != nil {
+		return err
+	}
+	if err := Utils.WriteObject(fs.file, &folder, blockPosition(fs.sb, number)); err != nil {
+		return err
+	}
+	if err := fs.attachNew(dir, number); err != nil {
+		return err
+	}
+	return fs.writeInode(index, dir)
+}
+
+// addEntry agrega name a una carpeta que todavía no está enlazada, como
+// las de copyTree.
+func (fs *FSWriter) addEntry(dirIndex int32, dir *Structs.Inode, name string, child int32) error {
+	link, err := fs.prepareEntry(dirIndex, dir, name, child)
+	if err != nil {
+		return err
+	}
+	return link()
+}
+
+// prepareEntry busca dónde agregar name a la carpeta, usando un espacio
+// libre o un bloque de carpeta nuevo, y escribe solo lo que nada apunta
+// todavía. La entrada aparece cuando se llama a link.
+func (fs *FSWriter) prepareEntry(dirIndex int32, dir *Structs.Inode, name string, child int32) (func() error, error) {
+	content := Structs.Content{B_inodo: child}
+	copy(content.B_name[:], name)
+
+	var free *dirEntry
+	err := walkFolderBlocks(fs.file, fs.sb, dir, func(block int32, folder *Structs.Folderblock) error {
+		for slot, c := range folder.B_content {
+			if c.B_inodo == -1 {
+				free = &dirEntry{block: block, slot: slot}
+				return StopWalk
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if free != nil {
+		return func() error {
+			return fs.writeEntry(free.block, free.slot, content)
+		}, nil
+	}
+
+	folder := emptyFolder()
+	folder.B_content[0] = content
+
+	number, err := fs.allocBlock()
+	if err != nil {
+		return nil, err
+	}
+	if err := Utils.WriteObject(fs.file, &folder, blockPosition(fs.sb, number)); err != nil {
+		return nil, err
+	}
+	attach, err := fs.attachBlock(dir, number)
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		if err := attach(); err != nil {
+			return err
+		}
+		return fs.writeInode(dirIndex, dir)
+	}, nil
+}
+
+// attachNew agrega el bloque a un inodo que todavía no está enlazado, así
+// que no hay nada que postergar.
+func (fs *FSWriter) attachNew(inode *Structs.Inode, block int32) error {
+	attach, err := fs.attachBlock(inode, block)
+	if err != nil {
+		return err
+	}
+	return attach()
+}
+
+// attachBlock pone el bloque en el primer apuntador libre del inodo en
+// memoria, creando los bloques de apuntadores que hagan falta. Los bloques
+// de apuntadores nuevos se escriben enseguida; el cambio en uno que ya
+// estaba enlazado se devuelve en attach para escribirlo con el inodo.
+func (fs *FSWriter) attachBlock(inode *Structs.Inode, block int32) (func() error, error) {
+	for i := 0; i < directBlocks; i++ {
+		if inode.I_block[i] == -1 {
+			inode.I_block[i] = block
+			return noWrite, nil
+		}
+	}
+
+	for level := 1; level <= 3; level++ {
+		slot := directBlocks + level - 1
+		linked := inode.I_block[slot] != -1
+		if !linked {
+			pointer, err := fs.newPointerBlock()
+			if err != nil {
+				return nil, err
+			}
+			inode.I_block[slot] = pointer
+		}
+
+		attached, attach, err := fs.attachInPointer(inode.I_block[slot], level, block, linked)
+		if err != nil || attached {
+			return attach, err
+		}
+	}
+
+	return nil, fmt.Errorf("%w: el inodo no admite más bloques", ErrNoFreeBlocks)
+}
+
+// attachInPointer agrega el bloque bajo el bloque de apuntadores pointer.
+// Si pointer ya está enlazado (linked), su escritura se devuelve en attach.
+func (fs *FSWriter) attachInPointer(pointer int32, level int, block int32, linked bool) (bool, func() error, error) {
+	var pointers Structs.Pointerblock
+	position := blockPosition(fs.sb, pointer)
+	if err := Utils.ReadObject(fs.file, &pointers, position); err != nil {
+		return false, nil, err
+	}
+
+	save := func() (bool, func() error, error) {
+		write := func() error {
+			return Utils.WriteObject(fs.file, &pointers, position)
+		}
+		if linked {
+			return true, write, nil
+		}
+		return true, noWrite, write()
+	}
+
+	for i, value := range pointers.B_pointers {
+		if level == 1 {
+			if value != -1 {
+				continue
+			}
+			pointers.B_pointers[i] = block
+			return save()
+		}
+
+		if value != -1 {
+			attached, attach, err := fs.attachInPointer(value, level-1, block, linked)
+			if err != nil || attached {
+				return attached, attach, err
+			}
+			continue
+		}
+
+		// El bloque de apuntadores nuevo se enlaza solo cuando ya tiene el
+		// bloque, para no dejar apuntadores a bloques libres si falta espacio.
+		child, err := fs.newPointerBlock()
+		if err != nil {
+			return false, nil, err
+		}
+		if _, _, err := fs.attachInPointer(child, level-1, block, false); err != nil {
+			return false, nil, err
+		}
+		pointers.B_pointers[i] = child
+		return save()
+	}
+
+	return false, noWrite, nil
+}
+
+func noWrite() error { return nil }
+
+func (fs *FSWriter) newPointerBlock() (int32, error) {
+	number, err := fs.allocBlock()
+	if err != nil {
+		return -1, err
+	}
+
+	var pointers Structs.Pointerblock
+	for i := range pointers.B_pointers {
+		pointers.B_pointers[i] = -1
+	}
+	return number, Utils.WriteObject(fs.file, &pointers, blockPosition(fs.sb, number))
+}
+
+func (fs *FSWriter) freeTree(index int32, inode *Structs.Inode) error {
+	if inode.I_type[0] == '0' {
+		children, err := fs.children(inode)
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			childInode, err := fs.readInode(child.content.B_inodo)
+			if err != nil {
+				return err
+			}
+			if err := fs.freeTree(child.content.B_inodo, childInode); err != nil {
+				return err
+			}
+		}
+	}
+
+	err := WalkInodeBlocks(fs.file, fs.sb, inode, func(ref BlockRef) error {
+		if ref.Block >= 0 && ref.Block < fs.sb.S_blocks_count {
+			fs.blockBitmap[ref.Block] = fs.blockStyle[0]
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fs.inodeBitmap[index] = fs.inodeStyle[0]
+	return nil
+}
+
+// checkTree valida el permiso sobre el inodo y, si es carpeta, sobre todo
+// su contenido.
+func (fs *FSWriter) checkTree(target string, inode *Structs.Inode, bit int) error {
+	if !fs.allowed(inode, bit) {
+		return fmt.Errorf("%w: %s", ErrPermissionDenied, target)
+	}
+	if inode.I_type[0] != '0' {
+		return nil
+	}
+
+	children, err := fs.children(inode)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		childInode, err := fs.readInode(child.content.B_inodo)
+		if err != nil {
+			return err
+		}
+		if err := fs.checkTree(path.Join(target, child.Name()), childInode, bit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fs *FSWriter) allowed(inode *Structs.Inode, bit int) bool {
+	return hasPermission(inode, fs.session, bit)
+}
+
+func (fs *FSWriter) lookupDir(dirPath string) (int32, *Structs.Inode, error) {
+	index, err := FindDirectoryInode(fs.file, fs.sb, dirPath)
+	if err != nil {
+		return -1, nil, fmt.Errorf("%w: %s", ErrFileNotFound, dirPath)
+	}
+
+	inode, err := fs.readInode(index)
+	if err != nil {
+		return -1, nil, err
+	}
+	if inode.I_type[0] != '0' {
+		return -1, nil, fmt.Errorf("%w: %s", ErrNotDirectory, dirPath)
+	}
+	return index, inode, nil
+}
+
+// lookupEntry devuelve la carpeta padre y la entrada de target en ella.
+func (fs *FSWriter) lookupEntry(target string) (int32, *Structs.Inode, *dirEntry, error) {
+	parentIndex, parent, err := fs.lookupDir(path.Dir(target))
+	if err != nil {
+		return -1, nil, nil, err
+	}
+
+	entry, exists, err := fs.findEntry(parent, path.Base(target))
+	if err != nil {
+		return -1, nil, nil, err
+	}
+	if !exists {
+		return -1, nil, nil, fmt.Errorf("%w: %s", ErrFileNotFound, target)
+	}
+	return parentIndex, parent, entry, nil
+}
+
+func (fs *FSWriter) findEntry(dir *Structs.Inode, name string) (*dirEntry, bool, error) {
+	entries, err := fs.entries(dir)
+	if err != nil {
+		return nil, false, err
+	}
+	for i := range entries {
+		if entries[i].Name() == name {
+			return &entries[i], true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// children devuelve las entradas de la carpeta sin "." ni "..".
+func (fs *FSWriter) children(dir *Structs.Inode) ([]dirEntry, error) {
+	entries, err := fs.entries(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var children []dirEntry
+	for _, entry := range entries {
+		if name := entry.Name(); name != "." && name != ".." && name != "" {
+			children = append(children, entry)
+		}
+	}
+	return children, nil
+}
+
+func (fs *FSWriter) entries(dir *Structs.Inode) ([]dirEntry, error) {
+	var entries []dirEntry
+	err := walkFolderBlocks(fs.file, fs.sb, dir, func(block int32, folder *Structs.Folderblock) error {
+		for slot, content := range folder.B_content {
+			if content.B_inodo != -1 {
+				entries = append(entries, dirEntry{block: block, slot: slot, content: content})
+			}
+		}
+		return nil
+	})
+	return entries, err
+}
+
+func (fs *FSWriter) writeEntry(block int32, slot int, content Structs.Content) error {
+	var folder Structs.Folderblock
+	position := blockPosition(fs.sb, block)
+	if err := Utils.ReadObject(fs.file, &folder, position); err != nil {
+		return err
+	}
+	folder.B_content[slot] = content
+	return Utils.WriteObject(fs.file, &folder, position)
+}
+
+func (fs *FSWriter) touch(index int32, inode *Structs.Inode) error {
+	inode.I_mtime = coreNow()
+	return fs.writeInode(index, inode)
+}
+
+func (fs *FSWriter) readInode(index int32) (*Structs.Inode, error) {
+	if index < 0 || index >= fs.sb.S_inodes_count {
+		return nil, fmt.Errorf("inodo %d fuera de rango", index)
+	}
+
+	var inode Structs.Inode
+	if err := Utils.ReadObject(fs.file, &inode, fs.inodePosition(index)); err != nil {
+		return nil, err
+	}
+	return &inode, nil
+}
+
+func (fs *FSWriter) writeInode(index int32, inode *Structs.Inode) error {
+	return Utils.WriteObject(fs.file, inode, fs.inodePosition(index))
+}
+
+func (fs *FSWriter) inodePosition(index int32) int64 {
+	return int64(fs.sb.S_inode_start) + int64(index)*int64(fs.sb.S_inode_size)
+}
+
+func (fs *FSWriter) allocInode() (int32, error) {
+	for i, b := range fs.inodeBitmap {
+		if !disk.BitmapUsed(b) {
+			fs.inodeBitmap[i] = fs.inodeStyle[1]
+			return int32(i), nil
+		}
+	}
+	return -1, ErrNoFreeInodes
+}
+
+func (fs *FSWriter) allocBlock() (int32, error) {
+	for i, b := range fs.blockBitmap {
+		if !disk.BitmapUsed(b) {
+			fs.blockBitmap[i] = fs.blockStyle[1]
+			return int32(i), nil
+		}
+	}
+	return -1, ErrNoFreeBlocks
+}
+
+func emptyFolder() Structs.Folderblock {
+	var folder Structs.Folderblock
+	for i := range folder.B_content {
+		folder.B_content[i].B_inodo = -1
+	}
+	return folder
+}
+
+func checkProtected(target string) error {
+	if path.Clean(target) == usersFile {
+		return fmt.Errorf("%w: %s", ErrProtected, usersFile)
+	}
+	return nil
+}
+
+// validName aplica el límite de B_name (12 bytes) del bloque de carpeta.
+func validName(name string) error {
+	var content Structs.Content
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") || len(name) > len(content.B_name) {
+		return fmt.Errorf("%w: %q (máximo %d caracteres, sin '/')", ErrInvalidName, name, len(content.B_name))
+	}
+	return nil
+}
+
+func coreNow() [17]byte {
+	var stamp [17]byte
+	copy(stamp[:], time.Now().Format("2006-01-02 15:04"))
+	return stamp
+}
+
+func countFree(bitmap []byte) (int32, int32) {
+	free, first := int32(0), int32(-1)
+	for i, b := range bitmap {
+		if disk.BitmapUsed(b) {
+			continue
+		}
+		if first == -1 {
+			first = int32(i)
+		}
+		free++
+	}
+	return free, first
+}
@@ -0,0 +1,524 @@
+package filemanag
+
+import (
+	Structs "Backend/FileSystem"
+	"Backend/Utils"
+	"Backend/api/handlers/disk"
+	"Backend/api/handlers/usermanag"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+var (
+	rootSession  = &usermanag.SessionInfo{Username: "root", UID: 1, GID: 1, IsRoot: true}
+	otherSession = &usermanag.SessionInfo{Username: "user", UID: 2, GID: 2}
+	// adminSession es root con otro UID, para distinguir el dueño de las
+	// copias.
+	adminSession = &usermanag.SessionInfo{Username: "admin", UID: 3, GID: 1, IsRoot: true}
+)
+
+// fsFixture arma / con users.txt, docs/a.txt (1000 bytes, usa el
+// indirecto) y docs/sub/b.txt.
+func fsFixture(t *testing.T, inodes, blocks int32) *disk.ResolvedPartition {
+	t.Helper()
+
+	img := newTestImage(t, inodes, blocks)
+	img.mkfile(0, "users.txt", []byte("1,G,root\n1,U,root,root,123\n"))
+	docs := img.mkdir(0, "docs")
+	img.mkfile(docs, "a.txt", testContent(1000))
+	sub := img.mkdir(docs, "sub")
+	img.mkfile(sub, "b.txt", []byte("hola"))
+	return img.finish()
+}
+
+// writeFS ejecuta op en un FSWriter y confirma solo si op termina bien,
+// igual que runFSWrite.
+func writeFS(t *testing.T, resolved *disk.ResolvedPartition, session *usermanag.SessionInfo, op func(*FSWriter) error) error {
+	t.Helper()
+
+	fs, err := OpenFSWriter(resolved, session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	if err := op(fs); err != nil {
+		return err
+	}
+	if err := fs.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	return nil
+}
+
+func readPath(t *testing.T, resolved *disk.ResolvedPartition, filePath string) []byte {
+	t.Helper()
+
+	file, sb, err := openPartitionDisk(resolved, os.O_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	index, err := FindDirectoryInode(file, sb, filePath)
+	if err != nil {
+		t.Fatalf("%s: %v", filePath, err)
+	}
+	reader, err := NewInodeReader(file, sb, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := reader.ReadRange(0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func pathExists(t *testing.T, resolved *disk.ResolvedPartition, filePath string) bool {
+	t.Helper()
+	_, err := StatPath(resolved, filePath)
+	return err == nil
+}
+
+// checkConsistency recorre el árbol desde la raíz y comprueba que los
+// bitmaps marquen exactamente los inodos y bloques alcanzables, y que los
+// contadores del superbloque coincidan con los bitmaps.
+func checkConsistency(t *testing.T, resolved *disk.ResolvedPartition) {
+	t.Helper()
+
+	file, sb, err := openPartitionDisk(resolved, os.O_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	inodeBitmap := make([]byte, sb.S_inodes_count)
+	blockBitmap := make([]byte, sb.S_blocks_count)
+	if _, err := file.ReadAt(inodeBitmap, int64(sb.S_bm_inode_start)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.ReadAt(blockBitmap, int64(sb.S_bm_block_start)); err != nil {
+		t.Fatal(err)
+	}
+
+	inodes := make(map[int32]bool)
+	blocks := make(map[int32]bool)
+
+	var visit func(index, parent int32)
+	visit = func(index, parent int32) {
+		if inodes[index] {
+			t.Fatalf("el inodo %d aparece dos veces", index)
+		}
+		inodes[index] = true
+
+		var inode Structs.Inode
+		if err := Utils.ReadObject(file, &inode, int64(sb.S_inode_start)+int64(index)*int64(sb.S_inode_size)); err != nil {
+			t.Fatal(err)
+		}
+		err := WalkInodeBlocks(file, sb, &inode, func(ref BlockRef) error {
+			if blocks[ref.Block] {
+				t.Fatalf("el bloque %d aparece dos veces", ref.Block)
+			}
+			blocks[ref.Block] = true
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if inode.I_type[0] != '0' {
+			return
+		}
+
+		err = walkFolderBlocks(file, sb, &inode, func(block int32, folder *Structs.Folderblock) error {
+			for _, content := range folder.B_content {
+				name := string(bytes.TrimRight(content.B_name[:], "\x00"))
+				switch {
+				case content.B_inodo == -1:
+				case name == ".":
+					if content.B_inodo != index {
+						t.Errorf("\".\" del inodo %d apunta a %d", index, content.B_inodo)
+					}
+				case name == "..":
+					if content.B_inodo != parent {
+						t.Errorf("\"..\" del inodo %d apunta a %d, se esperaba %d", index, content.B_inodo, parent)
+					}
+				default:
+					visit(content.B_inodo, index)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	visit(0, 0)
+
+	for i, b := range inodeBitmap {
+		if disk.BitmapUsed(b) != inodes[int32(i)] {
+			t.Errorf("inodo %d: bitmap %t, alcanzable %t", i, disk.BitmapUsed(b), inodes[int32(i)])
+		}
+	}
+	for i, b := range blockBitmap {
+		if disk.BitmapUsed(b) != blocks[int32(i)] {
+			t.Errorf("bloque %d: bitmap %t, alcanzable %t", i, disk.BitmapUsed(b), blocks[int32(i)])
+		}
+	}
+
+	freeInodes, _ := countFree(inodeBitmap)
+	freeBlocks, _ := countFree(blockBitmap)
+	if sb.S_free_inodes_count != freeInodes || sb.S_free_blocks_count != freeBlocks {
+		t.Errorf("libres en superbloque %d/%d, en bitmaps %d/%d", sb.S_free_inodes_count, sb.S_free_blocks_count, freeInodes, freeBlocks)
+	}
+}
+
+func TestFSWriterRename(t *testing.T) {
+	resolved := fsFixture(t, 16, 64)
+
+	var renamed string
+	err := writeFS(t, resolved, rootSession, func(fs *FSWriter) (err error) {
+		renamed, err = fs.Rename("/docs/a.txt", "c.txt")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if renamed != "/docs/c.txt" {
+		t.Errorf("ruta nueva = %s", renamed)
+	}
+	if pathExists(t, resolved, "/docs/a.txt") {
+		t.Error("/docs/a.txt sigue existiendo")
+	}
+	if got := readPath(t, resolved, "/docs/c.txt"); !bytes.Equal(got, testContent(1000)) {
+		t.Error("el contenido cambió al renombrar")
+	}
+	checkConsistency(t, resolved)
+}
+
+func TestFSWriterMove(t *testing.T) {
+	resolved := fsFixture(t, 16, 64)
+
+	err := writeFS(t, resolved, rootSession, func(fs *FSWriter) error {
+		if _, err := fs.Move("/docs/a.txt", "/"); err != nil {
+			return err
+		}
+		_, err := fs.Move("/docs/sub", "/")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, moved := range []string{"/a.txt", "/sub/b.txt"} {
+		if !pathExists(t, resolved, moved) {
+			t.Errorf("no existe %s", moved)
+		}
+	}
+	if pathExists(t, resolved, "/docs/sub") {
+		t.Error("/docs/sub sigue existiendo")
+	}
+	// checkConsistency verifica que ".." de /sub apunte ahora a la raíz.
+	checkConsistency(t, resolved)
+}
+
+// Move lee y reserva todo antes de escribir: si falla, el destino no queda
+// con una entrada a medias.
+func TestFSWriterMoveFailsBeforeLinking(t *testing.T) {
+	img := newTestImage(t, 16, 64)
+	img.mkdir(0, "docs")
+	img.link(0, "roto", 999)
+	resolved := img.finish()
+
+	before, err := os.ReadFile(resolved.DiskPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = writeFS(t, resolved, rootSession, func(fs *FSWriter) error {
+		_, err := fs.Move("/roto", "/docs")
+		return err
+	})
+	if err == nil {
+		t.Fatal("se esperaba error al leer el inodo 999")
+	}
+
+	after, err := os.ReadFile(resolved.DiskPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("Move falló pero modificó el disco")
+	}
+}
+
+// Con los 12 directos llenos, la carpeta nueva se cuelga del bloque de
+// apuntadores que ya existe; si falla antes de enlazar, ese bloque no cambia.
+func TestPrepareEntryDefersLinkedPointer(t *testing.T) {
+	img := newTestImage(t, 16, 64)
+	docs := img.mkdir(0, "docs")
+	for i := 0; i < directBlocks; i++ {
+		img.addBlock(docs, img.folderBlock(docs, 0))
+	}
+	resolved := img.finish()
+
+	fs, err := OpenFSWriter(resolved, rootSession)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	index, dir, err := fs.lookupDir("/docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ocupa todos los espacios libres para forzar un bloque de carpeta nuevo.
+	for i := 0; ; i++ {
+		entries, _ := fs.entries(dir)
+		blocks, _ := DataBlocks(fs.file, fs.sb, dir)
+		if len(entries) == len(blocks)*len(Structs.Folderblock{}.B_content) {
+			break
+		}
+		if err := fs.addEntry(index, dir, fmt.Sprintf("f%d", i), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pointer := dir.I_block[directBlocks]
+	readPointers := func() Structs.Pointerblock {
+		var pointers Structs.Pointerblock
+		if err := Utils.ReadObject(fs.file, &pointers, blockPosition(fs.sb, pointer)); err != nil {
+			t.Fatal(err)
+		}
+		return pointers
+	}
+	before := readPointers()
+
+	link, err := fs.prepareEntry(index, dir, "nuevo", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readPointers() != before {
+		t.Fatal("prepareEntry escribió en un bloque de apuntadores enlazado")
+	}
+
+	if err := link(); err != nil {
+		t.Fatal(err)
+	}
+	if readPointers() == before {
+		t.Error("link no enlazó el bloque de carpeta nuevo")
+	}
+	if _, exists, err := fs.findEntry(dir, "nuevo"); err != nil || !exists {
+		t.Errorf("no existe la entrada nueva: %v", err)
+	}
+}
+
+func TestFSWriterCopy(t *testing.T) {
+	resolved := fsFixture(t, 16, 64)
+
+	var copied string
+	err := writeFS(t, resolved, adminSession, func(fs *FSWriter) (err error) {
+		copied, err = fs.Copy("/docs", "/", false)
+		if !errors.Is(err, ErrIsDirectory) {
+			t.Errorf("copiar carpeta sin recursive = %v, se esperaba ErrIsDirectory", err)
+		}
+
+		if _, err := fs.Copy("/docs/a.txt", "/", false); err != nil {
+			return err
+		}
+		copied, err = fs.Copy("/docs/sub", "/", true)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if copied != "/sub" {
+		t.Errorf("ruta de la copia = %s", copied)
+	}
+	if got := readPath(t, resolved, "/a.txt"); !bytes.Equal(got, testContent(1000)) {
+		t.Error("la copia de a.txt no coincide con el original")
+	}
+	if got := readPath(t, resolved, "/sub/b.txt"); string(got) != "hola" {
+		t.Errorf("/sub/b.txt = %q", got)
+	}
+
+	original, err := StatPath(resolved, "/docs/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	duplicate, err := StatPath(resolved, "/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if duplicate.Inode == original.Inode {
+		t.Error("la copia comparte inodo con el original")
+	}
+	if duplicate.OwnerUID != "3" {
+		t.Errorf("dueño de la copia = %s, se esperaba el de la sesión", duplicate.OwnerUID)
+	}
+	checkConsistency(t, resolved)
+}
+
+func TestFSWriterRemove(t *testing.T) {
+	resolved := fsFixture(t, 16, 64)
+
+	err := writeFS(t, resolved, rootSession, func(fs *FSWriter) error {
+		return fs.Remove("/docs", false)
+	})
+	if !errors.Is(err, ErrNotEmpty) {
+		t.Fatalf("borrar carpeta con contenido = %v, se esperaba ErrNotEmpty", err)
+	}
+
+	err = writeFS(t, resolved, rootSession, func(fs *FSWriter) error {
+		if err := fs.Remove("/docs/a.txt", false); err != nil {
+			return err
+		}
+		return fs.Remove("/docs", true)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pathExists(t, resolved, "/docs") {
+		t.Error("/docs sigue existiendo")
+	}
+	checkConsistency(t, resolved)
+}
+
+func TestFSWriterErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		session *usermanag.SessionInfo
+		op      func(*FSWriter) error
+		want    error
+	}{
+		{
+			name:    "renombrar a un nombre existente",
+			session: rootSession,
+			op:      func(fs *FSWriter) error { _, err := fs.Rename("/docs/a.txt", "sub"); return err },
+			want:    ErrTargetExists,
+		},
+		{
+			name:    "nombre con barra",
+			session: rootSession,
+			op:      func(fs *FSWriter) error { _, err := fs.Rename("/docs/a.txt", "x/y"); return err },
+			want:    ErrInvalidName,
+		},
+		{
+			name:    "nombre demasiado largo",
+			session: rootSession,
+			op:      func(fs *FSWriter) error { _, err := fs.Rename("/docs/a.txt", "nombre-largo.txt"); return err },
+			want:    ErrInvalidName,
+		},
+		{
+			name:    "mover dentro de sí mismo",
+			session: rootSession,
+			op:      func(fs *FSWriter) error { _, err := fs.Move("/docs", "/docs/sub"); return err },
+			want:    ErrInvalidTarget,
+		},
+		{
+			name:    "mover a un archivo",
+			session: rootSession,
+			op:      func(fs *FSWriter) error { _, err := fs.Move("/docs/sub", "/docs/a.txt"); return err },
+			want:    ErrNotDirectory,
+		},
+		{
+			name:    "copiar dentro de sí mismo",
+			session: rootSession,
+			op:      func(fs *FSWriter) error { _, err := fs.Copy("/docs", "/docs/sub", true); return err },
+			want:    ErrInvalidTarget,
+		},
+		{
+			name:    "origen inexistente",
+			session: rootSession,
+			op:      func(fs *FSWriter) error { return fs.Remove("/nada.txt", false) },
+			want:    ErrFileNotFound,
+		},
+		{
+			name:    "borrar sin permiso",
+			session: otherSession,
+			op:      func(fs *FSWriter) error { return fs.Remove("/docs/a.txt", false) },
+			want:    ErrPermissionDenied,
+		},
+		{
+			name:    "borrar users.txt",
+			session: rootSession,
+			op:      func(fs *FSWriter) error { return fs.Remove("/users.txt", false) },
+			want:    ErrProtected,
+		},
+		{
+			name:    "renombrar users.txt",
+			session: rootSession,
+			op:      func(fs *FSWriter) error { _, err := fs.Rename("/users.txt", "u.txt"); return err },
+			want:    ErrProtected,
+		},
+		{
+			name:    "mover users.txt",
+			session: rootSession,
+			op:      func(fs *FSWriter) error { _, err := fs.Move("/users.txt", "/docs"); return err },
+			want:    ErrProtected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved := fsFixture(t, 16, 64)
+
+			if err := writeFS(t, resolved, tt.session, tt.op); !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, se esperaba %v", err, tt.want)
+			}
+			if !pathExists(t, resolved, "/users.txt") || !pathExists(t, resolved, "/docs/a.txt") {
+				t.Error("la operación fallida cambió el árbol")
+			}
+			checkConsistency(t, resolved)
+		})
+	}
+}
+
+// Una copia que se queda sin bloques a medias no debe dejar rastro.
+func TestFSWriterCopyNoSpace(t *testing.T) {
+	img := newTestImage(t, 16, 30)
+	img.mkfile(0, "a.txt", testContent(1000))
+	img.mkdir(0, "docs")
+	resolved := img.finish()
+
+	err := writeFS(t, resolved, rootSession, func(fs *FSWriter) error {
+		_, err := fs.Copy("/a.txt", "/docs", false)
+		return err
+	})
+	if !errors.Is(err, ErrNoFreeBlocks) {
+		t.Fatalf("error = %v, se esperaba ErrNoFreeBlocks", err)
+	}
+
+	if pathExists(t, resolved, "/docs/a.txt") {
+		t.Error("quedó una copia incompleta")
+	}
+	checkConsistency(t, resolved)
+}
+
+func TestOpenFSWriterRejects(t *testing.T) {
+	t.Run("EXT3", func(t *testing.T) {
+		img := newTestImage(t, 4, 8)
+		img.sb.S_filesystem_type = 3
+		resolved := img.finish()
+
+		if _, err := OpenFSWriter(resolved, rootSession); !errors.Is(err, ErrJournaled) {
+			t.Errorf("error = %v, se esperaba ErrJournaled", err)
+		}
+	})
+
+	t.Run("superbloque fuera de la partición", func(t *testing.T) {
+		img := newTestImage(t, 4, 8)
+		img.sb.S_blocks_count = 1 << 30
+		resolved := img.finish()
+
+		if _, err := OpenFSWriter(resolved, rootSession); err == nil {
+			t.Error("se esperaba error por el tamaño de los bitmaps")
+		}
+	})
+}
@@ -0,0 +1,263 @@
+package filemanag
+
+import (
+	Structs "Backend/FileSystem"
+	"Backend/Utils"
+	"Backend/api/handlers/disk"
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testImage arma una partición EXT2 mínima en un archivo temporal, con el
+// superbloque al inicio y bitmaps en ASCII como los escribe mkfs.
+type testImage struct {
+	t    *testing.T
+	path string
+	file *os.File
+	sb   Structs.Superblock
+
+	inodeBitmap []byte
+	blockBitmap []byte
+	nextInode   int32
+	nextBlock   int32
+}
+
+func newTestImage(t *testing.T, inodes, blocks int32) *testImage {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "A.dsk")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := &testImage{
+		t:           t,
+		path:        path,
+		file:        file,
+		inodeBitmap: bytes.Repeat([]byte{'0'}, int(inodes)),
+		blockBitmap: bytes.Repeat([]byte{'0'}, int(blocks)),
+	}
+
+	sb := &img.sb
+	sb.S_filesystem_type = 2
+	sb.S_magic = 0xEF53
+	sb.S_inodes_count = inodes
+	sb.S_blocks_count = blocks
+	sb.S_inode_size = int32(binary.Size(Structs.Inode{}))
+	sb.S_block_size = int32(binary.Size(Structs.Fileblock{}))
+	sb.S_bm_inode_start = int32(binary.Size(Structs.Superblock{}))
+	sb.S_bm_block_start = sb.S_bm_inode_start + inodes
+	sb.S_inode_start = sb.S_bm_block_start + blocks
+	sb.S_block_start = sb.S_inode_start + inodes*sb.S_inode_size
+
+	if err := file.Truncate(int64(sb.S_block_start) + int64(blocks)*int64(sb.S_block_size)); err != nil {
+		t.Fatal(err)
+	}
+
+	root := img.newInode('0', 0)
+	img.addBlock(root, img.folderBlock(root, root))
+	return img
+}
+
+func (img *testImage) resolved() *disk.ResolvedPartition {
+	info, err := img.file.Stat()
+	if err != nil {
+		img.t.Fatal(err)
+	}
+	return &disk.ResolvedPartition{
+		MountID:  "A1",
+		DiskID:   "A",
+		DiskPath: img.path,
+		Name:     "P1",
+		Record:   Structs.Partition{Start: 0, Size: int32(info.Size())},
+	}
+}
+
+// finish escribe bitmaps y superbloque y cierra el archivo.
+func (img *testImage) finish() *disk.ResolvedPartition {
+	img.t.Helper()
+
+	img.write(img.inodeBitmap, int64(img.sb.S_bm_inode_start))
+	img.write(img.blockBitmap, int64(img.sb.S_bm_block_start))
+	img.sb.S_free_inodes_count, img.sb.S_fist_ino = countFree(img.inodeBitmap)
+	img.sb.S_free_blocks_count, img.sb.S_first_blo = countFree(img.blockBitmap)
+	if err := Utils.WriteObject(img.file, &img.sb, 0); err != nil {
+		img.t.Fatal(err)
+	}
+
+	resolved := img.resolved()
+	img.file.Close()
+	return resolved
+}
+
+func (img *testImage) write(data []byte, offset int64) {
+	if _, err := img.file.WriteAt(data, offset); err != nil {
+		img.t.Fatal(err)
+	}
+}
+
+func (img *testImage) newInode(kind byte, size int32) int32 {
+	index := img.nextInode
+	img.nextInode++
+	img.inodeBitmap[index] = '1'
+
+	var inode Structs.Inode
+	inode.I_uid, inode.I_gid, inode.I_size = 1, 1, size
+	inode.I_type[0] = kind
+	copy(inode.I_perm[:], "664")
+	for i := range inode.I_block {
+		inode.I_block[i] = -1
+	}
+	img.writeInode(index, &inode)
+	return index
+}
+
+func (img *testImage) readInode(index int32) Structs.Inode {
+	var inode Structs.Inode
+	if err := Utils.ReadObject(img.file, &inode, int64(img.sb.S_inode_start)+int64(index)*int64(img.sb.S_inode_size)); err != nil {
+		img.t.Fatal(err)
+	}
+	return inode
+}
+
+func (img *testImage) writeInode(index int32, inode *Structs.Inode) {
+	if err := Utils.WriteObject(img.file, inode, int64(img.sb.S_inode_start)+int64(index)*int64(img.sb.S_inode_size)); err != nil {
+		img.t.Fatal(err)
+	}
+}
+
+func (img *testImage) allocBlock() int32 {
+	block := img.nextBlock
+	img.nextBlock++
+	img.blockBitmap[block] = '1'
+	return block
+}
+
+func (img *testImage) folderBlock(self, parent int32) int32 {
+	block := img.allocBlock()
+
+	var folder Structs.Folderblock
+	for i := range folder.B_content {
+		folder.B_content[i].B_inodo = -1
+	}
+	copy(folder.B_content[0].B_name[:], ".")
+	folder.B_content[0].B_inodo = self
+	copy(folder.B_content[1].B_name[:], "..")
+	folder.B_content[1].B_inodo = parent
+
+	img.writeObject(block, &folder)
+	return block
+}
+
+func (img *testImage) writeObject(block int32, data interface{}) {
+	if err := Utils.WriteObject(img.file, data, blockPosition(&img.sb, block)); err != nil {
+		img.t.Fatal(err)
+	}
+}
+
+// addBlock agrega un bloque de datos al inodo: primero los 12 directos y
+// luego el indirecto simple.
+func (img *testImage) addBlock(index, block int32) {
+	inode := img.readInode(index)
+
+	for i := 0; i < directBlocks; i++ {
+		if inode.I_block[i] == -1 {
+			inode.I_block[i] = block
+			img.writeInode(index, &inode)
+			return
+		}
+	}
+
+	var pointers Structs.Pointerblock
+	if inode.I_block[directBlocks] == -1 {
+		for i := range pointers.B_pointers {
+			pointers.B_pointers[i] = -1
+		}
+		inode.I_block[directBlocks] = img.allocBlock()
+		img.writeInode(index, &inode)
+	} else if err := Utils.ReadObject(img.file, &pointers, blockPosition(&img.sb, inode.I_block[directBlocks])); err != nil {
+		img.t.Fatal(err)
+	}
+
+	for i, pointer := range pointers.B_pointers {
+		if pointer == -1 {
+			pointers.B_pointers[i] = block
+			img.writeObject(inode.I_block[directBlocks], &pointers)
+			return
+		}
+	}
+	img.t.Fatalf("el inodo %d no tiene más apuntadores", index)
+}
+
+// link agrega name al primer hueco del directorio parent.
+func (img *testImage) link(parent int32, name string, child int32) {
+	inode := img.readInode(parent)
+	blocks, err := DataBlocks(img.file, &img.sb, &inode)
+	if err != nil {
+		img.t.Fatal(err)
+	}
+
+	for _, block := range blocks {
+		var folder Structs.Folderblock
+		if err := Utils.ReadObject(img.file, &folder, blockPosition(&img.sb, block)); err != nil {
+			img.t.Fatal(err)
+		}
+		for i := range folder.B_content {
+			if folder.B_content[i].B_inodo == -1 {
+				copy(folder.B_content[i].B_name[:], name)
+				folder.B_content[i].B_inodo = child
+				img.writeObject(block, &folder)
+				return
+			}
+		}
+	}
+
+	block := img.allocBlock()
+	var folder Structs.Folderblock
+	for i := range folder.B_content {
+		folder.B_content[i].B_inodo = -1
+	}
+	copy(folder.B_content[0].B_name[:], name)
+	folder.B_content[0].B_inodo = child
+	img.writeObject(block, &folder)
+	img.addBlock(parent, block)
+}
+
+func (img *testImage) mkdir(parent int32, name string) int32 {
+	index := img.newInode('0', 0)
+	img.addBlock(index, img.folderBlock(index, parent))
+	img.link(parent, name, index)
+	return index
+}
+
+func (img *testImage) mkfile(parent int32, name string, content []byte) int32 {
+	index := img.newInode('1', int32(len(content)))
+
+	chunk := int(img.sb.S_block_size)
+	for start := 0; start < len(content); start += chunk {
+		end := start + chunk
+		if end > len(content) {
+			end = len(content)
+		}
+
+		block := img.allocBlock()
+		img.write(content[start:end], blockPosition(&img.sb, block))
+		img.addBlock(index, block)
+	}
+
+	img.link(parent, name, index)
+	return index
+}
+
+// testContent devuelve n bytes distintos entre bloques vecinos.
+func testContent(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte('a' + i%26 + i/64%3)
+	}
+	return data
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"Backend/api/handlers/console"
+	"Backend/api/handlers/disk"
+	"Backend/api/handlers/filemanag"
+	"Backend/api/handlers/usermanag"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// mkdir se ejecuta con el comando de la consola. Renombrar, mover, copiar y
+// borrar no tienen comando en el núcleo y se hacen con filemanag.FSWriter,
+// con los mismos permisos UGO que aplica el núcleo. Como no pasan por el
+// journal, solo se permiten en EXT2.
+
+type MkdirRequest struct {
+	Path    string `json:"path"`
+	Parents bool   `json:"parents,omitempty"`
+}
+
+type RenameRequest struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+}
+
+type MoveRequest struct {
+	Path        string `json:"path"`
+	Destination string `json:"destination"`
+}
+
+type CopyRequest struct {
+	Path        string `json:"path"`
+	Destination string `json:"destination"`
+	Recursive   bool   `json:"recursive,omitempty"`
+}
+
+type RemovePathResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Path    string `json:"path"`
+}
+
+func MakeDirectory(w http.ResponseWriter, r *http.Request) {
+	var req MkdirRequest
+	if !decodeFSRequest(w, r, &req) {
+		return
+	}
+
+	resolved, _, dirPath, ok := requireSessionPartition(w, r, req.Path)
+	if !ok {
+		return
+	}
+
+	if _, err := filemanag.StatPath(resolved, dirPath); err == nil {
+		writeAPIError(w, http.StatusConflict, APIError{Error: fmt.Sprintf("ya existe %s", dirPath), Code: ErrTargetExists})
+		return
+	}
+
+	command := commandLine("mkdir", "path", dirPath)
+	if req.Parents {
+		command += " -r"
+	}
+
+	explain := func() (ErrorCode, string, bool) {
+		return explainCreateInPartition(resolved, dirPath, req.Parents)
+	}
+	if _, ok := runTypedCommand(w, r, command, explain); !ok {
+		return
+	}
+
+	console.Printf("Carpeta %s creada en %s desde la API\n", dirPath, resolved.MountID)
+	writeFSItem(w, http.StatusCreated, resolved, dirPath)
+}
+
+func RenamePath(w http.ResponseWriter, r *http.Request) {
+	var req RenameRequest
+	if !decodeFSRequest(w, r, &req) {
+		return
+	}
+
+	coreMu.Lock()
+	defer coreMu.Unlock()
+
+	resolved, session, target, ok := requireSessionPartition(w, r, req.Path)
+	if !ok {
+		return
+	}
+
+	var renamed string
+	if !runFSWrite(w, session, resolved, func(fs *filemanag.FSWriter) (err error) {
+		renamed, err = fs.Rename(target, req.Name)
+		return err
+	}) {
+		return
+	}
+
+	console.Printf("%s renombrado a %s en %s\n", target, renamed, resolved.MountID)
+	writeFSItem(w, http.StatusOK, resolved, renamed)
+}
+
+func MovePath(w http.ResponseWriter, r *http.Request) {
+	var req MoveRequest
+	if !decodeFSRequest(w, r, &req) {
+		return
+	}
+
+	coreMu.Lock()
+	defer coreMu.Unlock()
+
+	resolved, session, target, ok := requireSessionPartition(w, r, req.Path)
+	if !ok {
+		return
+	}
+	destination, ok := cleanDestination(w, req.Destination)
+	if !ok {
+		return
+	}
+
+	var moved string
+	if !runFSWrite(w, session, resolved, func(fs *filemanag.FSWriter) (err error) {
+		moved, err = fs.Move(target, destination)
+		return err
+	}) {
+		return
+	}
+
+	console.Printf("%s movido a %s en %s\n", target, moved, resolved.MountID)
+	writeFSItem(w, http.StatusOK, resolved, moved)
+}
+
+func CopyPath(w http.ResponseWriter, r *http.Request) {
+	var req CopyRequest
+	if !decodeFSRequest(w, r, &req) {
+		return
+	}
+
+	coreMu.Lock()
+	defer coreMu.Unlock()
+
+	resolved, session, target, ok := requireSessionPartition(w, r, req.Path)
+	if !ok {
+		return
+	}
+	destination, ok := cleanDestination(w, req.Destination)
+	if !ok {
+		return
+	}
+
+	var copied string
+	if !runFSWrite(w, session, resolved, func(fs *filemanag.FSWriter) (err error) {
+		copied, err = fs.Copy(target, destination, req.Recursive)
+		return err
+	}) {
+		return
+	}
+
+	console.Printf("%s copiado a %s en %s\n", target, copied, resolved.MountID)
+	writeFSItem(w, http.StatusCreated, resolved, copied)
+}
+
+// RemovePath borra ?path=; las carpetas con contenido requieren
+// ?recursive=true.
+func RemovePath(w http.ResponseWriter, r *http.Request) {
+	coreMu.Lock()
+	defer coreMu.Unlock()
+
+	resolved, session, target, ok := requireSessionPartition(w, r, r.URL.Query().Get("path"))
+	if !ok {
+		return
+	}
+	recursive := r.URL.Query().Get("recursive") == "true"
+
+	if !runFSWrite(w, session, resolved, func(fs *filemanag.FSWriter) error {
+		return fs.Remove(target, recursive)
+	}) {
+		return
+	}
+
+	console.Printf("%s eliminado de %s\n", target, resolved.MountID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RemovePathResponse{
+		Success: true,
+		Message: fmt.Sprintf("%s eliminado", target),
+		Path:    target,
+	})
+}
+
+// runFSWrite ejecuta op y confirma los bitmaps solo si op termina bien.
+// Requiere coreMu tomado en escritura desde antes de resolver la partición,
+// para que nadie más escriba entre la validación y op.
+func runFSWrite(w http.ResponseWriter, session *usermanag.SessionInfo, resolved *disk.ResolvedPartition, op func(*filemanag.FSWriter) error) bool {
+	fs, err := filemanag.OpenFSWriter(resolved, session)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, APIError{Error: err.Error(), Code: ErrInternal})
+		return false
+	}
+	defer fs.Close()
+
+	if err := op(fs); err != nil {
+		code := fsErrorCode(err)
+		writeAPIError(w, httpStatusForCode(code), APIError{Error: err.Error(), Code: code})
+		return false
+	}
+
+	if err := fs.Commit(); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, APIError{Error: err.Error(), Code: ErrInternal})
+		return false
+	}
+	return true
+}
+
+func fsErrorCode(err error) ErrorCode {
+	switch {
+	case errors.Is(err, filemanag.ErrFileNotFound):
+		return ErrNotFound
+	case errors.Is(err, filemanag.ErrPermissionDenied), errors.Is(err, filemanag.ErrProtected):
+		return ErrPermissionDenied
+	case errors.Is(err, filemanag.ErrTargetExists):
+		return ErrTargetExists
+	case errors.Is(err, filemanag.ErrNotEmpty):
+		return ErrNotEmpty
+	case errors.Is(err, filemanag.ErrNoFreeInodes):
+		return ErrNoFreeInodes
+	case errors.Is(err, filemanag.ErrNoFreeBlocks):
+		return ErrNoFreeBlocks
+	case errors.Is(err, filemanag.ErrJournaled):
+		return ErrUnsupportedFS
+	case errors.Is(err, filemanag.ErrNotDirectory), errors.Is(err, filemanag.ErrIsDirectory),
+		errors.Is(err, filemanag.ErrInvalidName), errors.Is(err, filemanag.ErrInvalidTarget):
+		return ErrInvalidParameters
+	default:
+		return ErrInternal
+	}
+}
+
+func decodeFSRequest(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, APIError{Error: "Request JSON inválido", Code: ErrInvalidParameters})
+		return false
+	}
+	return true
+}
+
+// cleanDestination acepta la raíz, a diferencia de cleanPartitionPath.
+func cleanDestination(w http.ResponseWriter, raw string) (string, bool) {
+	if !strings.HasPrefix(raw, "/") {
+		writeAPIError(w, http.StatusBadRequest, APIError{Error: "destination debe ser una ruta absoluta", Code: ErrInvalidParameters})
+		return "", false
+	}
+	return path.Clean(raw), true
+}
+
+func writeFSItem(w http.ResponseWriter, status int, resolved *disk.ResolvedPartition, itemPath string) {
+	item, err := filemanag.StatPath(resolved, itemPath)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, APIError{Error: err.Error(), Code: ErrInternal})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(item)
+}
@@ -0,0 +1,562 @@
+package handlers
+
+import (
+	Structs "Backend/FileSystem"
+	"Backend/Utils"
+	"Backend/api/handlers/console"
+	"Backend/api/handlers/disk"
+	"Backend/api/handlers/filemanag"
+	"Backend/api/handlers/usermanag"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	FsckFreeCountMismatch = "FREE_COUNT_MISMATCH"
+	FsckDanglingEntry     = "DANGLING_ENTRY"
+	FsckInvalidEntry      = "INVALID_ENTRY"
+	FsckOrphanInode       = "ORPHAN_INODE"
+	FsckOrphanBlock       = "ORPHAN_BLOCK"
+	FsckUnmarkedBlock     = "UNMARKED_BLOCK"
+	FsckInvalidBlock      = "INVALID_BLOCK"
+	FsckDuplicateBlock    = "DUPLICATE_BLOCK"
+	FsckBadDot            = "BAD_DOT"
+	FsckBadDotDot         = "BAD_DOTDOT"
+	FsckUnreadable        = "UNREADABLE"
+)
+
+type FsckRequest struct {
+	Repair bool `json:"repair"`
+}
+
+type FsckIssue struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Inode    *int32 `json:"inode,omitempty"`
+	Block    *int32 `json:"block,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Repaired bool   `json:"repaired"`
+}
+
+type FsckReport struct {
+	PartitionID     string      `json:"partition_id"`
+	Repair          bool        `json:"repair"`
+	Clean           bool        `json:"clean"`
+	InodesCount     int32       `json:"inodes_count"`
+	BlocksCount     int32       `json:"blocks_count"`
+	ReachableInodes int         `json:"reachable_inodes"`
+	ClaimedBlocks   int         `json:"claimed_blocks"`
+	Repaired        int         `json:"repaired"`
+	Unrepaired      int         `json:"unrepaired"`
+	Issues          []FsckIssue `json:"issues"`
+}
+
+// CheckFilesystem revisa la consistencia de una partición formateada. Con
+// repair=true corrige contadores, bitmaps, entradas "." y "..", y libera
+// inodos y bloques huérfanos; los bloques compartidos solo se reportan.
+// Reparar requiere una sesión root en la misma partición y una partición
+// EXT2: las reparaciones no quedarían en el journal de una EXT3.
+func CheckFilesystem(w http.ResponseWriter, r *http.Request) {
+	partitionID := mux.Vars(r)["id"]
+
+	var req FsckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeAPIError(w, http.StatusBadRequest, APIError{Error: "Request JSON inválido", Code: ErrInvalidParameters})
+		return
+	}
+	if r.URL.Query().Get("repair") == "true" {
+		req.Repair = true
+	}
+
+	var session *usermanag.SessionInfo
+	if req.Repair {
+		var ok bool
+		session, ok = usermanag.SessionFromRequest(r)
+		if !ok {
+			writeAPIError(w, http.StatusUnauthorized, APIError{Error: "no hay sesión activa", Code: ErrNoSession})
+			return
+		}
+		if !session.IsRoot {
+			writeAPIError(w, http.StatusForbidden, APIError{Error: "solo el usuario root puede reparar una partición", Code: ErrPermissionDenied})
+			return
+		}
+
+		coreMu.Lock()
+		defer coreMu.Unlock()
+	} else {
+		coreMu.RLock()
+		defer coreMu.RUnlock()
+	}
+
+	resolved, err := disk.ResolveMountID(partitionID)
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, APIError{Error: err.Error(), Code: ErrNotFound})
+		return
+	}
+
+	if req.Repair && !strings.EqualFold(session.PartitionID, resolved.MountID) {
+		writeAPIError(w, http.StatusForbidden, APIError{
+			Error: fmt.Sprintf("solo se puede reparar la partición de la sesión (%s)", session.PartitionID),
+			Code:  ErrPermissionDenied,
+		})
+		return
+	}
+
+	flags := os.O_RDONLY
+	if req.Repair {
+		flags = os.O_RDWR
+	}
+	file, err := os.OpenFile(resolved.DiskPath, flags, 0644)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, APIError{Error: err.Error(), Code: ErrInternal})
+		return
+	}
+	defer file.Close()
+
+	checker, err := newFsckChecker(file, &resolved.Record, req.Repair)
+	if errors.Is(err, filemanag.ErrJournaled) {
+		writeAPIError(w, http.StatusConflict, APIError{Error: err.Error(), Code: ErrUnsupportedFS})
+		return
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusConflict, APIError{Error: err.Error(), Code: ErrCommandFailed})
+		return
+	}
+
+	report, err := checker.run()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, APIError{Error: err.Error(), Code: ErrInternal})
+		return
+	}
+	report.PartitionID = resolved.MountID
+	if report.PartitionID == "" {
+		report.PartitionID = partitionID
+	}
+
+	if req.Repair && report.Repaired > 0 {
+		console.Printf("fsck: %d problemas reparados en %s\n", report.Repaired, report.PartitionID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+type fsckChecker struct {
+	file   *os.File
+	sbPos  int64
+	sb     Structs.Superblock
+	repair bool
+
+	inodeBitmap []byte
+	blockBitmap []byte
+
+	reachable map[int32]bool
+	owners    map[int32]int32
+	report    FsckReport
+
+	// folderFixes son los problemas cuya reparación depende de escribir el
+	// bloque de carpeta que se está revisando.
+	folderFixes []int
+}
+
+type fsckDir struct {
+	inode  int32
+	parent int32
+	path   string
+}
+
+func newFsckChecker(file *os.File, partition *Structs.Partition, repair bool) (*fsckChecker, error) {
+	c := &fsckChecker{
+		file:      file,
+		sbPos:     int64(partition.Start),
+		repair:    repair,
+		reachable: make(map[int32]bool),
+		owners:    make(map[int32]int32),
+	}
+
+	if err := Utils.ReadObject(file, &c.sb, c.sbPos); err != nil {
+		return nil, fmt.Errorf("no se pudo leer el superbloque: %v", err)
+	}
+	if c.sb.S_magic != 0xEF53 {
+		return nil, fmt.Errorf("la partición no está formateada")
+	}
+	if repair && c.sb.S_filesystem_type == 3 {
+		return nil, filemanag.ErrJournaled
+	}
+	if err := disk.CheckSuperblockLayout(&c.sb, partition); err != nil {
+		return nil, err
+	}
+
+	c.inodeBitmap = make([]byte, c.sb.S_inodes_count)
+	c.blockBitmap = make([]byte, c.sb.S_blocks_count)
+	if _, err := file.ReadAt(c.inodeBitmap, int64(c.sb.S_bm_inode_start)); err != nil {
+		return nil, fmt.Errorf("no se pudo leer el bitmap de inodos: %v", err)
+	}
+	if _, err := file.ReadAt(c.blockBitmap, int64(c.sb.S_bm_block_start)); err != nil {
+		return nil, fmt.Errorf("no se pudo leer el bitmap de bloques: %v", err)
+	}
+
+	c.report = FsckReport{
+		Repair:      repair,
+		InodesCount: c.sb.S_inodes_count,
+		BlocksCount: c.sb.S_blocks_count,
+		Issues:      []FsckIssue{},
+	}
+	return c, nil
+}
+
+func (c *fsckChecker) run() (*FsckReport, error) {
+	bitmapsDirty := false
+
+	if !disk.BitmapUsed(c.inodeBitmap[0]) {
+		issue := FsckIssue{Code: FsckDanglingEntry, Inode: ptr32(0), Path: "/",
+			Message: "el inodo raíz está libre en el bitmap"}
+		if c.repair {
+			c.inodeBitmap[0] = disk.BitmapValue(c.inodeBitmap, true)
+			issue.Repaired, bitmapsDirty = true, true
+		}
+		c.add(issue)
+	}
+
+	queue := []fsckDir{{inode: 0, parent: 0, path: "/"}}
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+
+		children, dirty := c.checkInode(dir)
+		bitmapsDirty = bitmapsDirty || dirty
+		queue = append(queue, children...)
+	}
+
+	for i := int32(0); i < c.sb.S_inodes_count; i++ {
+		if disk.BitmapUsed(c.inodeBitmap[i]) && !c.reachable[i] {
+			issue := FsckIssue{Code: FsckOrphanInode, Inode: ptr32(i),
+				Message: fmt.Sprintf("el inodo %d está marcado como usado pero ningún directorio lo referencia", i)}
+			if c.repair {
+				c.inodeBitmap[i] = disk.BitmapValue(c.inodeBitmap, false)
+				issue.Repaired, bitmapsDirty = true, true
+			}
+			c.add(issue)
+		}
+	}
+
+	for i := int32(0); i < c.sb.S_blocks_count; i++ {
+		if _, claimed := c.owners[i]; disk.BitmapUsed(c.blockBitmap[i]) && !claimed {
+			issue := FsckIssue{Code: FsckOrphanBlock, Block: ptr32(i),
+				Message: fmt.Sprintf("el bloque %d está marcado como usado pero ningún inodo lo usa", i)}
+			if c.repair {
+				c.blockBitmap[i] = disk.BitmapValue(c.blockBitmap, false)
+				issue.Repaired, bitmapsDirty = true, true
+			}
+			c.add(issue)
+		}
+	}
+
+	if bitmapsDirty {
+		if _, err := c.file.WriteAt(c.inodeBitmap, int64(c.sb.S_bm_inode_start)); err != nil {
+			return nil, err
+		}
+		if _, err := c.file.WriteAt(c.blockBitmap, int64(c.sb.S_bm_block_start)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.checkFreeCounts(); err != nil {
+		return nil, err
+	}
+
+	c.report.ReachableInodes = len(c.reachable)
+	c.report.ClaimedBlocks = len(c.owners)
+	c.report.Clean = len(c.report.Issues) == 0
+	return &c.report, nil
+}
+
+// checkInode marca el inodo como alcanzable, reclama sus bloques y, si es
+// un directorio, revisa sus entradas. Devuelve los subdirectorios y archivos
+// a visitar.
+func (c *fsckChecker) checkInode(dir fsckDir) ([]fsckDir, bool) {
+	if c.reachable[dir.inode] {
+		return nil, false
+	}
+	c.reachable[dir.inode] = true
+
+	inode, err := c.readInode(dir.inode)
+	if err != nil {
+		c.add(FsckIssue{Code: FsckUnreadable, Inode: ptr32(dir.inode), Path: dir.path,
+			Message: fmt.Sprintf("no se pudo leer el inodo %d: %v", dir.inode, err)})
+		return nil, false
+	}
+
+	var dataBlocks []int32
+	dirty := c.claimBlocks(dir, inode, func(block int32) {
+		dataBlocks = append(dataBlocks, block)
+	})
+
+	if inode.I_type[0] != '0' {
+		return nil, dirty
+	}
+
+	var children []fsckDir
+	for n, block := range dataBlocks {
+		folder, err := c.readFolder(block)
+		if err != nil {
+			c.add(FsckIssue{Code: FsckUnreadable, Inode: ptr32(dir.inode), Block: ptr32(block), Path: dir.path,
+				Message: fmt.Sprintf("no se pudo leer el bloque de carpeta %d: %v", block, err)})
+			continue
+		}
+
+		folderDirty := false
+		c.folderFixes = c.folderFixes[:0]
+		for i := range folder.B_content {
+			entry := &folder.B_content[i]
+			name := strings.Trim(string(entry.B_name[:]), "\x00")
+
+			if n == 0 && i < 2 {
+				folderDirty = c.checkDotEntry(dir, block, i, entry) || folderDirty
+				continue
+			}
+			if entry.B_inodo == -1 || name == "" {
+				continue
+			}
+
+			childPath := path.Join(dir.path, name)
+			keep, markUsed := c.checkEntry(dir, block, childPath, entry)
+			if markUsed {
+				dirty = true
+			}
+			if !keep {
+				if c.repair {
+					*entry = Structs.Content{B_inodo: -1}
+					folderDirty = true
+				}
+				continue
+			}
+			children = append(children, fsckDir{inode: entry.B_inodo, parent: dir.inode, path: childPath})
+		}
+
+		if folderDirty {
+			if err := c.writeBlock(block, &folder); err != nil {
+				console.Printf("fsck: error escribiendo bloque %d: %v\n", block, err)
+				c.revertFolderFixes(err)
+			}
+		}
+	}
+
+	return children, dirty
+}
+
+func (c *fsckChecker) checkDotEntry(dir fsckDir, block int32, index int, entry *Structs.Content) bool {
+	wantName, want, code := ".", dir.inode, FsckBadDot
+	if index == 1 {
+		wantName, want, code = "..", dir.parent, FsckBadDotDot
+	}
+
+	name := strings.Trim(string(entry.B_name[:]), "\x00")
+	if name == wantName && entry.B_inodo == want {
+		return false
+	}
+
+	issue := FsckIssue{Code: code, Inode: ptr32(dir.inode), Block: ptr32(block), Path: dir.path,
+		Message: fmt.Sprintf("la entrada %q de %s apunta a %q (inodo %d), debería ser el inodo %d", wantName, dir.path, name, entry.B_inodo, want)}
+	if c.repair {
+		*entry = Structs.Content{B_inodo: want}
+		copy(entry.B_name[:], wantName)
+		issue.Repaired = true
+	}
+	c.addFolderFix(issue)
+	return issue.Repaired
+}
+
+// checkEntry valida el inodo al que apunta una entrada. keep indica si la
+// entrada debe recorrerse; markUsed si se marcó el inodo en el bitmap.
+func (c *fsckChecker) checkEntry(dir fsckDir, block int32, childPath string, entry *Structs.Content) (keep, markUsed bool) {
+	target := entry.B_inodo
+
+	if target < 0 || target >= c.sb.S_inodes_count {
+		issue := FsckIssue{Code: FsckInvalidEntry, Inode: ptr32(dir.inode), Block: ptr32(block), Path: childPath,
+			Message: fmt.Sprintf("%s apunta al inodo %d, fuera del rango 0-%d", childPath, target, c.sb.S_inodes_count-1)}
+		issue.Repaired = c.repair
+		c.addFolderFix(issue)
+		return false, false
+	}
+
+	if disk.BitmapUsed(c.inodeBitmap[target]) {
+		return true, false
+	}
+
+	issue := FsckIssue{Code: FsckDanglingEntry, Inode: ptr32(target), Path: childPath,
+		Message: fmt.Sprintf("%s apunta al inodo %d, que está libre en el bitmap", childPath, target)}
+
+	// Si el inodo todavía tiene un tipo válido se conserva marcándolo como
+	// usado; si no, se elimina la entrada.
+	child, err := c.readInode(target)
+	valid := err == nil && (child.I_type[0] == '0' || child.I_type[0] == '1')
+	if c.repair {
+		issue.Repaired = true
+		if valid {
+			c.inodeBitmap[target] = disk.BitmapValue(c.inodeBitmap, true)
+		}
+	}
+
+	if !valid {
+		// La entrada se borra al escribir el bloque de carpeta.
+		c.addFolderFix(issue)
+		return false, false
+	}
+	c.add(issue)
+	return true, c.repair
+}
+
+// claimBlocks registra cada bloque del inodo, de datos o de apuntadores,
+// usando el mismo recorrido que el explorador.
+func (c *fsckChecker) claimBlocks(dir fsckDir, inode *Structs.Inode, data func(int32)) bool {
+	dirty := false
+
+	sb := c.sb
+	err := filemanag.WalkInodeBlocks(c.file, &sb, inode, func(ref filemanag.BlockRef) error {
+		ok, marked := c.claim(dir, ref.Block)
+		dirty = dirty || marked
+		if !ok {
+			return filemanag.SkipBlock
+		}
+		if !ref.Pointer {
+			data(ref.Block)
+		}
+		return nil
+	})
+	if err != nil {
+		c.add(FsckIssue{Code: FsckUnreadable, Inode: ptr32(dir.inode), Path: dir.path, Message: err.Error()})
+	}
+
+	return dirty
+}
+
+// claim registra que el inodo usa el bloque. Devuelve false si el bloque no
+// se debe seguir leyendo (fuera de rango o ya usado por otro inodo).
+func (c *fsckChecker) claim(dir fsckDir, block int32) (ok, marked bool) {
+	if block < 0 || block >= c.sb.S_blocks_count {
+		c.add(FsckIssue{Code: FsckInvalidBlock, Inode: ptr32(dir.inode), Block: ptr32(block), Path: dir.path,
+			Message: fmt.Sprintf("%s apunta al bloque %d, fuera del rango 0-%d", dir.path, block, c.sb.S_blocks_count-1)})
+		return false, false
+	}
+
+	if owner, claimed := c.owners[block]; claimed {
+		c.add(FsckIssue{Code: FsckDuplicateBlock, Inode: ptr32(dir.inode), Block: ptr32(block), Path: dir.path,
+			Message: fmt.Sprintf("el bloque %d lo usan los inodos %d y %d", block, owner, dir.inode)})
+		return false, false
+	}
+	c.owners[block] = dir.inode
+
+	if disk.BitmapUsed(c.blockBitmap[block]) {
+		return true, false
+	}
+
+	issue := FsckIssue{Code: FsckUnmarkedBlock, Inode: ptr32(dir.inode), Block: ptr32(block), Path: dir.path,
+		Message: fmt.Sprintf("el bloque %d lo usa %s pero está libre en el bitmap", block, dir.path)}
+	if c.repair {
+		c.blockBitmap[block] = disk.BitmapValue(c.blockBitmap, true)
+		issue.Repaired = true
+	}
+	c.add(issue)
+	return true, issue.Repaired
+}
+
+func (c *fsckChecker) checkFreeCounts() error {
+	freeInodes, freeBlocks := int32(0), int32(0)
+	for _, b := range c.inodeBitmap {
+		if !disk.BitmapUsed(b) {
+			freeInodes++
+		}
+	}
+	for _, b := range c.blockBitmap {
+		if !disk.BitmapUsed(b) {
+			freeBlocks++
+		}
+	}
+
+	dirty := false
+	if c.sb.S_free_inodes_count != freeInodes {
+		issue := FsckIssue{Code: FsckFreeCountMismatch,
+			Message: fmt.Sprintf("el superbloque indica %d inodos libres y el bitmap tiene %d", c.sb.S_free_inodes_count, freeInodes)}
+		if c.repair {
+			c.sb.S_free_inodes_count = freeInodes
+			issue.Repaired, dirty = true, true
+		}
+		c.add(issue)
+	}
+	if c.sb.S_free_blocks_count != freeBlocks {
+		issue := FsckIssue{Code: FsckFreeCountMismatch,
+			Message: fmt.Sprintf("el superbloque indica %d bloques libres y el bitmap tiene %d", c.sb.S_free_blocks_count, freeBlocks)}
+		if c.repair {
+			c.sb.S_free_blocks_count = freeBlocks
+			issue.Repaired, dirty = true, true
+		}
+		c.add(issue)
+	}
+
+	if dirty {
+		return Utils.WriteObject(c.file, &c.sb, c.sbPos)
+	}
+	return nil
+}
+
+func (c *fsckChecker) add(issue FsckIssue) {
+	if issue.Repaired {
+		c.report.Repaired++
+	} else {
+		c.report.Unrepaired++
+	}
+	c.report.Issues = append(c.report.Issues, issue)
+}
+
+func (c *fsckChecker) addFolderFix(issue FsckIssue) {
+	if issue.Repaired {
+		c.folderFixes = append(c.folderFixes, len(c.report.Issues))
+	}
+	c.add(issue)
+}
+
+// revertFolderFixes deja como no reparados los problemas del bloque de
+// carpeta que no se pudo escribir.
+func (c *fsckChecker) revertFolderFixes(err error) {
+	for _, i := range c.folderFixes {
+		issue := &c.report.Issues[i]
+		issue.Repaired = false
+		issue.Message += fmt.Sprintf(" (no se pudo reparar: %v)", err)
+		c.report.Repaired--
+		c.report.Unrepaired++
+	}
+	c.folderFixes = c.folderFixes[:0]
+}
+
+func (c *fsckChecker) readInode(index int32) (*Structs.Inode, error) {
+	var inode Structs.Inode
+	err := Utils.ReadObject(c.file, &inode, int64(c.sb.S_inode_start)+int64(index)*int64(c.sb.S_inode_size))
+	return &inode, err
+}
+
+func (c *fsckChecker) readFolder(block int32) (Structs.Folderblock, error) {
+	var folder Structs.Folderblock
+	err := Utils.ReadObject(c.file, &folder, c.blockPos(block))
+	return folder, err
+}
+
+func (c *fsckChecker) writeBlock(block int32, data interface{}) error {
+	return Utils.WriteObject(c.file, data, c.blockPos(block))
+}
+
+func (c *fsckChecker) blockPos(block int32) int64 {
+	return int64(c.sb.S_block_start) + int64(block)*int64(c.sb.S_block_size)
+}
+
+func ptr32(v int32) *int32 {
+	return &v
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	Structs "Backend/FileSystem"
+	"Backend/Utils"
+	"Backend/api/handlers/filemanag"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Una partición EXT3 se puede revisar pero no reparar: las escrituras de
+// fsck no quedarían en el journal.
+func TestFsckRepairRejectsExt3(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "A.dsk"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	sb := Structs.Superblock{S_filesystem_type: 3, S_magic: 0xEF53}
+	if err := Utils.WriteObject(file, &sb, 0); err != nil {
+		t.Fatal(err)
+	}
+	partition := &Structs.Partition{Start: 0, Size: 4096}
+
+	if _, err := newFsckChecker(file, partition, true); !errors.Is(err, filemanag.ErrJournaled) {
+		t.Errorf("reparar EXT3: error = %v, se esperaba ErrJournaled", err)
+	}
+	if _, err := newFsckChecker(file, partition, false); errors.Is(err, filemanag.ErrJournaled) {
+		t.Errorf("revisar EXT3 sin reparar se rechazó: %v", err)
+	}
+}
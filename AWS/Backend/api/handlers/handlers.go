@@ -1,23 +1,29 @@
 package handlers
 
 import (
-	"Backend/Analyzer"
+	"Backend/api/handlers/usermanag"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strings"
 )
 
 type CommandRequest struct {
-	Command string `json:"command"`
+	Command       string   `json:"command"`
+	OnError       string   `json:"on_error,omitempty"`
+	StopOn        []string `json:"stop_on,omitempty"`
+	Transactional bool     `json:"transactional,omitempty"`
 }
 
 type CommandResponse struct {
-	Output  string `json:"output"`
-	Success bool   `json:"success"`
-	Error   string `json:"error,omitempty"`
+	Output  string        `json:"output"`
+	Success bool          `json:"success"`
+	Error   string        `json:"error,omitempty"`
+	Status  CommandStatus `json:"status"`
+	Code    ErrorCode     `json:"code,omitempty"`
+	Message string        `json:"message,omitempty"`
+	Data    *CommandData  `json:"data,omitempty"`
 }
 
 func ExecuteCommand(w http.ResponseWriter, r *http.Request) {
@@ -28,49 +34,87 @@ func ExecuteCommand(w http.ResponseWriter, r *http.Request) {
 		response := CommandResponse{
 			Success: false,
 			Error:   "Request JSON inválido",
+			Status:  StatusFailed,
+			Code:    ErrInvalidCommand,
 		}
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
-	if req.Command == "" {
+	statements, err := LexScript(req.Command)
+	if err != nil {
 		response := CommandResponse{
 			Success: false,
-			Error:   "Comando vacío",
+			Error:   err.Error(),
+			Status:  StatusFailed,
+			Code:    ErrSyntax,
+			Message: err.Error(),
 		}
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
-	oldStdout := os.Stdout
-	r_pipe, w_pipe, _ := os.Pipe()
-	os.Stdout = w_pipe
+	if len(statements) == 0 {
+		response := CommandResponse{
+			Success: false,
+			Error:   "Comando vacío",
+			Status:  StatusFailed,
+			Code:    ErrInvalidCommand,
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
 
-	Analyzer.ProcessCommand(req.Command)
+	caller := usermanag.CallerFromRequest(w, r)
 
-	w_pipe.Close()
-	os.Stdout = oldStdout
+	var output strings.Builder
+	var outcome CommandOutcome
 
-	output, _ := io.ReadAll(r_pipe)
-	outputString := string(output)
+	// Con varios comandos se informa el primero que falle o, si ninguno
+	// falla, el primero que no se pudo verificar.
+	for i, statement := range statements {
+		result := runCommand(caller, io.Discard, statement.Command)
+		output.WriteString(result.Output)
 
-	success := !strings.Contains(outputString, "Error:") &&
-		!strings.Contains(outputString, "==========Error:")
+		if i == 0 || statusRank(result.Outcome.Status) > statusRank(outcome.Status) {
+			outcome = result.Outcome
+		}
+	}
 
 	response := CommandResponse{
-		Output:  outputString,
-		Success: success,
+		Output:  output.String(),
+		Success: outcome.Success(),
+		Status:  outcome.Status,
+		Code:    outcome.Code,
+		Message: outcome.Message,
+		Data:    &outcome.Data,
+	}
+
+	if outcome.Status == StatusFailed {
+		response.Error = outcome.Message
 	}
 
-	if !success {
-		response.Error = "El comando falló - revisar output para detalles"
+	if outcome.Code == ErrInternal {
+		w.WriteHeader(http.StatusInternalServerError)
 	}
 
 	json.NewEncoder(w).Encode(response)
 }
 
+func statusRank(status CommandStatus) int {
+	switch status {
+	case StatusSuccess:
+		return 0
+	case StatusUnknown:
+		return 1
+	default:
+		return 2
+	}
+}
+
 func FormatFileSize(bytes int64) string {
 	const (
 		KB = 1024
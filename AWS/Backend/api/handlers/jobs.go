@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"Backend/api/handlers/console"
+	"Backend/api/handlers/usermanag"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+const defaultJobRetention = 30 * time.Minute
+
+// JobKeyHeader lleva la clave de un trabajo enviado sin sesión.
+const JobKeyHeader = "X-Job-Key"
+
+type JobRequest struct {
+	Command       string   `json:"command"`
+	Script        string   `json:"script"`
+	OnError       string   `json:"on_error,omitempty"`
+	StopOn        []string `json:"stop_on,omitempty"`
+	Transactional bool     `json:"transactional,omitempty"`
+}
+
+type JobInfo struct {
+	ID              string                   `json:"id"`
+	Status          JobStatus                `json:"status"`
+	Source          string                   `json:"source"`
+	TotalCommands   int                      `json:"total_commands"`
+	CurrentLine     int                      `json:"current_line"`
+	CurrentCommand  string                   `json:"current_command,omitempty"`
+	CompletedCount  int                      `json:"completed_commands"`
+	SuccessCommands int                      `json:"success_commands"`
+	FailedCommands  int                      `json:"failed_commands"`
+	UnknownCommands int                      `json:"unknown_commands"`
+	Results         []CommandExecutionResult `json:"results"`
+	Summary         *BatchExecuteResult      `json:"summary,omitempty"`
+	CreatedAt       string                   `json:"created_at"`
+	StartedAt       string                   `json:"started_at,omitempty"`
+	FinishedAt      string                   `json:"finished_at,omitempty"`
+	ExpiresAt       string                   `json:"expires_at,omitempty"`
+	// Key solo se devuelve al encolar un trabajo sin sesión; hay que
+	// enviarla en X-Job-Key para consultarlo o cancelarlo.
+	Key string `json:"key,omitempty"`
+}
+
+type batchJob struct {
+	mu       sync.Mutex
+	info     JobInfo
+	cancel   context.CancelFunc
+	finished time.Time
+	owner    jobOwner
+}
+
+// jobOwner identifica a quien envió el trabajo: el usuario y la partición
+// de su sesión o, si no tenía, una clave propia del trabajo.
+type jobOwner struct {
+	username    string
+	partitionID string
+	key         string
+}
+
+var (
+	jobsMu       sync.Mutex
+	jobs         = make(map[string]*batchJob)
+	jobRetention = jobRetentionFromEnv()
+)
+
+func SubmitJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req JobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Request JSON inválido", http.StatusBadRequest)
+		return
+	}
+
+	caller := usermanag.CallerFromRequest(nil, r)
+
+	var lines []batchLine
+	var source string
+	fallback := defaultStringPolicy
+
+	switch {
+	case strings.TrimSpace(req.Script) != "":
+		scriptLines, err := splitBatchString(req.Script)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		lines = scriptLines
+		source = "script"
+	case IsBatchCommand(req.Command):
+		path, _, _ := ParseExecuteCommand(req.Command)
+		fileLines, err := readBatchFile(caller, path)
+		if err != nil {
+			http.Error(w, err.Error(), scriptErrorStatus(err))
+			return
+		}
+		lines = fileLines
+		source = path
+		fallback = defaultFilePolicy
+	default:
+		http.Error(w, "Se requiere 'script' o un comando execute con -path", http.StatusBadRequest)
+		return
+	}
+
+	policy, err := ParseErrorPolicy(req.OnError, req.StopOn, fallback)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	purgeExpiredJobs()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &batchJob{
+		info: JobInfo{
+			ID:            newJobID(),
+			Status:        JobQueued,
+			Source:        source,
+			TotalCommands: len(lines),
+			Results:       []CommandExecutionResult{},
+			CreatedAt:     time.Now().Format(time.RFC3339),
+		},
+		cancel: cancel,
+	}
+
+	if session, ok := usermanag.SessionFromRequest(r); ok {
+		job.owner = jobOwner{username: session.Username, partitionID: session.PartitionID}
+	} else {
+		job.owner = jobOwner{key: newJobKey()}
+	}
+
+	jobsMu.Lock()
+	jobs[job.info.ID] = job
+	jobsMu.Unlock()
+
+	// El trabajo sigue después de responder, así que no puede escribir en
+	// w: corre con una copia de la sesión y sus login o logout no cambian
+	// la del cliente.
+	go job.run(ctx, caller.Fork(), lines, policy, req.Transactional)
+
+	console.Printf("Trabajo %s encolado: %d comandos (%s)\n", job.info.ID, len(lines), source)
+
+	info := job.snapshot()
+	info.Key = job.owner.key
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(info)
+}
+
+func GetJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	purgeExpiredJobs()
+
+	job, ok := findOwnJob(r)
+	if !ok {
+		http.Error(w, "Trabajo no encontrado", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(job.snapshot())
+}
+
+func CancelJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	job, ok := findOwnJob(r)
+	if !ok {
+		http.Error(w, "Trabajo no encontrado", http.StatusNotFound)
+		return
+	}
+
+	job.cancel()
+	console.Printf("Cancelación solicitada para trabajo %s\n", job.info.ID)
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job.snapshot())
+}
+
+func (j *batchJob) run(ctx context.Context, caller *usermanag.Caller, lines []batchLine, policy ErrorPolicy, transactional bool) {
+	defer j.cancel()
+	defer caller.Discard()
+
+	j.mu.Lock()
+	j.info.Status = JobRunning
+	j.info.StartedAt = time.Now().Format(time.RFC3339)
+	j.mu.Unlock()
+
+	result := runBatch(caller, lines, batchOptions{
+		ctx:           ctx,
+		policy:        policy,
+		pause:         50 * time.Millisecond,
+		transactional: transactional,
+		hooks: batchHooks{
+			onStart: func(line batchLine) {
+				j.mu.Lock()
+				j.info.CurrentLine = line.Number
+				j.info.CurrentCommand = line.Command
+				j.mu.Unlock()
+			},
+			onFinish: func(res CommandExecutionResult) {
+				j.mu.Lock()
+				j.info.Results = append(j.info.Results, res)
+				j.info.CompletedCount++
+				switch res.Status {
+				case StatusSuccess:
+					j.info.SuccessCommands++
+				case StatusUnknown:
+					j.info.UnknownCommands++
+				default:
+					j.info.FailedCommands++
+				}
+				j.mu.Unlock()
+			},
+		},
+	})
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.finished = time.Now()
+	j.info.Status = JobCompleted
+	if result.StopReason == StopCancelled {
+		j.info.Status = JobCancelled
+	}
+	j.info.CurrentCommand = ""
+	j.info.Summary = result
+	j.info.FinishedAt = j.finished.Format(time.RFC3339)
+	j.info.ExpiresAt = j.finished.Add(jobRetention).Format(time.RFC3339)
+
+	console.Printf("Trabajo %s terminado (%s): %d/%d comandos exitosos\n",
+		j.info.ID, j.info.Status, result.SuccessCommands, result.TotalCommands)
+}
+
+func (j *batchJob) snapshot() JobInfo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	info := j.info
+	info.Results = append([]CommandExecutionResult(nil), j.info.Results...)
+	return info
+}
+
+func findJob(id string) (*batchJob, bool) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+
+	job, ok := jobs[id]
+	return job, ok
+}
+
+// findOwnJob busca el trabajo de la ruta y comprueba que sea de quien lo
+// pide. Un trabajo ajeno se trata como inexistente.
+func findOwnJob(r *http.Request) (*batchJob, bool) {
+	job, ok := findJob(mux.Vars(r)["id"])
+	if !ok {
+		return nil, false
+	}
+
+	owner := job.owner
+	if owner.key != "" {
+		key := r.Header.Get(JobKeyHeader)
+		if subtle.ConstantTimeCompare([]byte(key), []byte(owner.key)) != 1 {
+			return nil, false
+		}
+		return job, true
+	}
+
+	session, ok := usermanag.SessionFromRequest(r)
+	if !ok || session.Username != owner.username || session.PartitionID != owner.partitionID {
+		return nil, false
+	}
+	return job, true
+}
+
+func purgeExpiredJobs() {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+
+	now := time.Now()
+	for id, job := range jobs {
+		job.mu.Lock()
+		expired := !job.finished.IsZero() && now.Sub(job.finished) > jobRetention
+		job.mu.Unlock()
+
+		if expired {
+			delete(jobs, id)
+		}
+	}
+}
+
+func jobRetentionFromEnv() time.Duration {
+	value := os.Getenv("JOB_RETENTION")
+	if value == "" {
+		return defaultJobRetention
+	}
+
+	retention, err := time.ParseDuration(value)
+	if err != nil || retention <= 0 {
+		console.Printf("JOB_RETENTION inválido (%q), usando %s\n", value, defaultJobRetention)
+		return defaultJobRetention
+	}
+
+	return retention
+}
+
+func newJobKey() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("no se pudo generar clave de trabajo: %v", err))
+	}
+	return hex.EncodeToString(buf)
+}
+
+func newJobID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("job-%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	Structs "Backend/FileSystem"
+	"Backend/api/handlers/console"
+	"Backend/api/handlers/disk"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type CreatePartitionRequest struct {
+	Name string `json:"name"`
+	Size int    `json:"size"`
+	Unit string `json:"unit,omitempty"`
+	Type string `json:"type,omitempty"`
+	Fit  string `json:"fit,omitempty"`
+}
+
+type ResizePartitionRequest struct {
+	Add  int    `json:"add"`
+	Unit string `json:"unit,omitempty"`
+}
+
+// CreatePartition crea una partición con fdisk y devuelve la lista
+// actualizada de particiones del disco.
+func CreatePartition(w http.ResponseWriter, r *http.Request) {
+	diskID, path, ok := requireDisk(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreatePartitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, APIError{Error: "Request JSON inválido", Code: ErrInvalidParameters})
+		return
+	}
+
+	if strings.TrimSpace(req.Name) == "" {
+		writeAPIError(w, http.StatusBadRequest, APIError{Error: "falta el nombre de la partición", Code: ErrInvalidParameters})
+		return
+	}
+
+	partType := strings.ToUpper(req.Type)
+	if partType == "" {
+		partType = "P"
+	}
+
+	command := commandLine("fdisk",
+		"size", strconv.Itoa(req.Size),
+		"driveletter", diskID,
+		"name", req.Name,
+		"unit", strings.ToUpper(req.Unit),
+		"type", partType,
+		"fit", strings.ToUpper(req.Fit),
+	)
+
+	explain := func() (ErrorCode, string, bool) {
+		return explainCreateFailure(path, req.Name, partType, int64(req.Size)*unitBytes(req.Unit))
+	}
+	if _, ok := runTypedCommand(w, r, command, explain); !ok {
+		return
+	}
+
+	console.Printf("Partición %s creada en disco %s desde la API\n", req.Name, diskID)
+	writePartitionList(w, http.StatusCreated, diskID)
+}
+
+// DeletePartition elimina una partición con fdisk -delete=full.
+func DeletePartition(w http.ResponseWriter, r *http.Request) {
+	diskID, _, ok := requireDisk(w, r)
+	if !ok {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	if _, ok := requirePartition(w, diskID, name); !ok {
+		return
+	}
+
+	command := commandLine("fdisk", "delete", "full", "driveletter", diskID, "name", name)
+	if _, ok := runTypedCommand(w, r, command, nil); !ok {
+		return
+	}
+
+	console.Printf("Partición %s eliminada de disco %s desde la API\n", name, diskID)
+	writePartitionList(w, http.StatusOK, diskID)
+}
+
+// ResizePartition agrega o quita espacio con fdisk -add.
+func ResizePartition(w http.ResponseWriter, r *http.Request) {
+	diskID, path, ok := requireDisk(w, r)
+	if !ok {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	entry, ok := requirePartition(w, diskID, name)
+	if !ok {
+		return
+	}
+
+	var req ResizePartitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, APIError{Error: "Request JSON inválido", Code: ErrInvalidParameters})
+		return
+	}
+
+	command := commandLine("fdisk",
+		"add", strconv.Itoa(req.Add),
+		"unit", strings.ToUpper(req.Unit),
+		"driveletter", diskID,
+		"name", name,
+	)
+
+	explain := func() (ErrorCode, string, bool) {
+		return explainResizeFailure(path, entry, int64(req.Add)*unitBytes(req.Unit))
+	}
+	if _, ok := runTypedCommand(w, r, command, explain); !ok {
+		return
+	}
+
+	console.Printf("Partición %s de disco %s redimensionada (%+d %s) desde la API\n", name, diskID, req.Add, req.Unit)
+	writePartitionList(w, http.StatusOK, diskID)
+}
+
+func requireDisk(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	diskID, path, err := disk.ResolveDiskID(mux.Vars(r)["diskId"])
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, APIError{Error: err.Error(), Code: ErrNotFound})
+		return "", "", false
+	}
+
+	return diskID, path, true
+}
+
+func requirePartition(w http.ResponseWriter, diskID, name string) (partitionEntry, bool) {
+	resolved, err := disk.ResolvePartitionName(diskID, name)
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, APIError{
+			Error: fmt.Sprintf("la partición %s no existe en el disco %s", name, diskID),
+			Code:  ErrNotFound,
+		})
+		return partitionEntry{}, false
+	}
+
+	record := resolved.Record
+	return partitionEntry{
+		Name:  resolved.Name,
+		Type:  strings.ToUpper(string(record.Type[:])),
+		Start: record.Start,
+		Size:  record.Size,
+	}, true
+}
+
+func writePartitionList(w http.ResponseWriter, status int, diskID string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(disk.ListPartitions(diskID))
+}
+
+// fdisk usa K por defecto.
+func unitBytes(unit string) int64 {
+	switch strings.ToUpper(unit) {
+	case "B":
+		return 1
+	case "M":
+		return 1024 * 1024
+	default:
+		return 1024
+	}
+}
+
+func explainCreateFailure(path, name, partType string, size int64) (ErrorCode, string, bool) {
+	entries := readPartitionEntries(path)
+
+	if _, exists := entries[name]; exists {
+		return ErrDuplicateName, fmt.Sprintf("ya existe una partición llamada %s", name), true
+	}
+
+	var primaries []partitionEntry
+	var extended *partitionEntry
+	var logicals []partitionEntry
+	for _, entry := range entries {
+		switch entry.Type {
+		case "L":
+			logicals = append(logicals, entry)
+		case "E":
+			e := entry
+			extended = &e
+			primaries = append(primaries, entry)
+		default:
+			primaries = append(primaries, entry)
+		}
+	}
+
+	switch partType {
+	case "L":
+		if extended == nil {
+			return ErrNoExtended, "no hay partición extendida para crear una lógica", true
+		}
+
+		used := int64(0)
+		for _, logical := range logicals {
+			used += int64(logical.Size) + int64(binary.Size(Structs.EBR{}))
+		}
+		free := int64(extended.Size) - used - int64(binary.Size(Structs.EBR{}))
+		if size > free {
+			return ErrNoSpace, fmt.Sprintf("no hay espacio en la extendida: se piden %d bytes y quedan %d", size, free), true
+		}
+	default:
+		if len(primaries) >= 4 {
+			return ErrPartitionLimit, "el disco ya tiene 4 particiones primarias o extendidas", true
+		}
+		if partType == "E" && extended != nil {
+			return ErrExtendedExists, fmt.Sprintf("el disco ya tiene la extendida %s", extended.Name), true
+		}
+
+		largest := largestGap(path, primaries)
+		if size > largest {
+			return ErrNoSpace, fmt.Sprintf("no hay espacio contiguo suficiente: se piden %d bytes y el mayor hueco libre es de %d", size, largest), true
+		}
+	}
+
+	return "", "", false
+}
+
+func explainResizeFailure(path string, entry partitionEntry, delta int64) (ErrorCode, string, bool) {
+	if int64(entry.Size)+delta <= 0 {
+		return ErrInvalidParameters, fmt.Sprintf("la partición %s quedaría sin espacio (tamaño actual %d bytes)", entry.Name, entry.Size), true
+	}
+	if delta <= 0 {
+		return "", "", false
+	}
+
+	limit := diskFileSize(path)
+	for _, other := range readPartitionEntries(path) {
+		sameLevel := (other.Type == "L") == (entry.Type == "L")
+		if sameLevel && other.Start > entry.Start && int64(other.Start) < limit {
+			limit = int64(other.Start)
+		}
+		if entry.Type == "L" && other.Type == "E" {
+			if end := int64(other.Start) + int64(other.Size); end < limit {
+				limit = end
+			}
+		}
+	}
+
+	free := limit - (int64(entry.Start) + int64(entry.Size))
+	if delta > free {
+		return ErrNoSpace, fmt.Sprintf("solo hay %d bytes libres después de %s y se piden %d", free, entry.Name, delta), true
+	}
+
+	return "", "", false
+}
+
+// largestGap calcula el mayor espacio libre entre el MBR y el final del
+// disco sin contar las particiones existentes.
+func largestGap(path string, partitions []partitionEntry) int64 {
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].Start < partitions[j].Start
+	})
+
+	position := int64(binary.Size(Structs.MRB{}))
+	largest := int64(0)
+
+	for _, partition := range partitions {
+		if gap := int64(partition.Start) - position; gap > largest {
+			largest = gap
+		}
+		if end := int64(partition.Start) + int64(partition.Size); end > position {
+			position = end
+		}
+	}
+
+	if gap := diskFileSize(path) - position; gap > largest {
+		largest = gap
+	}
+	return largest
+}
+
+func diskFileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"Backend/Utils"
+	"Backend/api/handlers/usermanag"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	errScriptNeedsSession = errors.New("leer un script del servidor requiere una sesión activa; envíe el contenido en 'script' o como archivo")
+	errScriptOutsideDir   = errors.New("execute -path solo puede leer scripts del directorio de scripts")
+)
+
+// scriptDirectory es el único directorio del que execute -path lee scripts:
+// SCRIPT_DIR o, si no está definida, "scripts" junto al directorio de discos.
+func scriptDirectory() string {
+	if dir := os.Getenv("SCRIPT_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(Utils.GetDiskDirectory()), "scripts")
+}
+
+// resolveScriptPath devuelve la ruta real de un script del directorio de
+// scripts. Una ruta relativa se toma desde ese directorio; una que sale de
+// él, también por enlaces simbólicos, se rechaza antes de abrirla para no
+// revelar qué archivos existen en el servidor.
+func resolveScriptPath(path string) (string, error) {
+	root, err := filepath.Abs(scriptDirectory())
+	if err != nil {
+		return "", err
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	path = filepath.Clean(path)
+	if !insideDir(root, path) {
+		return "", errScriptOutsideDir
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("no existe el directorio de scripts %s", root)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("no existe el script %s", path)
+	}
+	if !insideDir(realRoot, resolved) {
+		return "", errScriptOutsideDir
+	}
+
+	return resolved, nil
+}
+
+func insideDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// readScriptFile lee un script del servidor para execute -path. Requiere
+// que el cliente tenga sesión.
+func readScriptFile(caller *usermanag.Caller, path string) (string, error) {
+	if _, ok := caller.Session(); !ok {
+		return "", errScriptNeedsSession
+	}
+
+	resolved, err := resolveScriptPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(resolved)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", fmt.Errorf("no existe el script %s", path)
+	}
+	if info.Size() > maxScriptUploadSize {
+		return "", fmt.Errorf("el script %s supera %s", path, FormatFileSize(maxScriptUploadSize))
+	}
+
+	content, err := os.ReadFile(resolved)
+	if err != nil {
+		return "", fmt.Errorf("no se pudo abrir archivo: %v", err)
+	}
+
+	return string(content), nil
+}
+
+func scriptErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errScriptNeedsSession):
+		return http.StatusUnauthorized
+	case errors.Is(err, errScriptOutsideDir):
+		return http.StatusForbidden
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"Backend/UserManagement"
+	"Backend/api/handlers/usermanag"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveScriptPath(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	t.Setenv("SCRIPT_DIR", root)
+
+	if err := os.WriteFile(filepath.Join(root, "a.mia"), []byte("mkdisk -size=5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(outside, "secreto.txt")
+	if err := os.WriteFile(secret, []byte("clave\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(root, "enlace.mia")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		outside bool
+		wantErr bool
+	}{
+		{name: "absoluta", path: filepath.Join(root, "a.mia"), want: "a.mia"},
+		{name: "relativa", path: "a.mia", want: "a.mia"},
+		{name: "fuera del directorio", path: secret, outside: true},
+		{name: "subiendo con ..", path: "../" + filepath.Base(outside) + "/secreto.txt", outside: true},
+		{name: "archivo del sistema", path: "/etc/passwd", outside: true},
+		{name: "enlace hacia afuera", path: "enlace.mia", outside: true},
+		{name: "no existe", path: "otro.mia", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveScriptPath(tt.path)
+			if tt.outside {
+				if !errors.Is(err, errScriptOutsideDir) {
+					t.Fatalf("resolveScriptPath(%q) = %q, %v; se esperaba errScriptOutsideDir", tt.path, got, err)
+				}
+				return
+			}
+			if tt.wantErr {
+				if err == nil || errors.Is(err, errScriptOutsideDir) {
+					t.Fatalf("resolveScriptPath(%q) = %q, %v", tt.path, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if filepath.Base(got) != tt.want {
+				t.Errorf("resolveScriptPath(%q) = %q", tt.path, got)
+			}
+		})
+	}
+}
+
+func TestReadScriptFileNeedsSession(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("SCRIPT_DIR", root)
+	if err := os.WriteFile(filepath.Join(root, "a.mia"), []byte("logout\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	anonymous := usermanag.CallerFromRequest(nil, r)
+	if _, err := readScriptFile(anonymous, "a.mia"); !errors.Is(err, errScriptNeedsSession) {
+		t.Fatalf("sin sesión: %v", err)
+	}
+
+	caller := usermanag.CallerFromRequest(httptest.NewRecorder(), r)
+	caller.Run(func() {
+		UserManagement.CurrentSession = UserManagement.Session{Username: "root", PartitionID: "A1", IsRoot: true}
+	})
+	defer caller.Discard()
+
+	content, err := readScriptFile(caller, "a.mia")
+	if err != nil || content != "logout\n" {
+		t.Fatalf("con sesión = %q, %v", content, err)
+	}
+	if _, err := readScriptFile(caller, "/etc/passwd"); !errors.Is(err, errScriptOutsideDir) {
+		t.Errorf("con sesión fuera del directorio: %v", err)
+	}
+}
+
+// El validador repite las líneas del script: no debe abrir archivos del
+// servidor para un cliente sin sesión.
+func TestValidateScriptRejectsHostFiles(t *testing.T) {
+	t.Setenv("SCRIPT_DIR", t.TempDir())
+
+	r := httptest.NewRequest(http.MethodPost, "/api/scripts/validate", strings.NewReader(`{"command":"execute -path=/etc/passwd"}`))
+	w := httptest.NewRecorder()
+	ValidateScript(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, se esperaba 401: %s", w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String(), "root:") {
+		t.Errorf("la respuesta contiene el archivo: %s", w.Body)
+	}
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ScriptToken struct {
+	Text   string `json:"text"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// ScriptStatement es un comando del script ya separado. Command es el texto
+// normalizado que se envía a Analyzer; Raw es el texto tal como se escribió.
+type ScriptStatement struct {
+	Line    int           `json:"line"`
+	Column  int           `json:"column"`
+	Raw     string        `json:"raw"`
+	Command string        `json:"command"`
+	Tokens  []ScriptToken `json:"tokens"`
+	Comment string        `json:"comment,omitempty"`
+}
+
+type ScriptSyntaxError struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+func (e *ScriptSyntaxError) Error() string {
+	return fmt.Sprintf("error de sintaxis en línea %d, columna %d: %s", e.Line, e.Column, e.Message)
+}
+
+// LexScript separa un script en comandos. Reconoce comillas simples y
+// dobles, comentarios '#' al final de la línea (y '//' al inicio de un
+// comando), continuación con '\' al final de la línea y separadores ';' o
+// salto de línea. No hay escapes: fuera de la continuación '\' es un
+// carácter más (C:\discos\a.dsk), y como el núcleo solo entiende valores
+// entre comillas dobles sin escapes, un valor no puede contener '"'.
+func LexScript(input string) ([]ScriptStatement, error) {
+	lx := &scriptLexer{input: []rune(input), line: 1, column: 1}
+	return lx.run()
+}
+
+// LexCommand separa un único comando; falla si el texto contiene varios.
+func LexCommand(command string) (ScriptStatement, error) {
+	statements, err := LexScript(command)
+	if err != nil {
+		return ScriptStatement{}, err
+	}
+
+	switch len(statements) {
+	case 0:
+		return ScriptStatement{}, nil
+	case 1:
+		return statements[0], nil
+	default:
+		second := statements[1]
+		return ScriptStatement{}, &ScriptSyntaxError{
+			Line:    second.Line,
+			Column:  second.Column,
+			Message: "se esperaba un solo comando",
+		}
+	}
+}
+
+type scriptLexer struct {
+	input  []rune
+	pos    int
+	line   int
+	column int
+
+	statements []ScriptStatement
+	current    *ScriptStatement
+	raw        strings.Builder
+
+	word     strings.Builder
+	inWord   bool
+	wordLine int
+	wordCol  int
+}
+
+func (lx *scriptLexer) peek(offset int) (rune, bool) {
+	if lx.pos+offset >= len(lx.input) {
+		return 0, false
+	}
+	return lx.input[lx.pos+offset], true
+}
+
+func (lx *scriptLexer) advance() rune {
+	r := lx.input[lx.pos]
+	lx.pos++
+	if r == '\n' {
+		lx.line++
+		lx.column = 1
+	} else {
+		lx.column++
+	}
+	return r
+}
+
+func (lx *scriptLexer) run() ([]ScriptStatement, error) {
+	for lx.pos < len(lx.input) {
+		r, _ := lx.peek(0)
+
+		switch {
+		case r == '\\' && lx.continuation():
+			// Ya se consumió; cualquier otra '\' es parte de la palabra.
+		case r == '"' || r == '\'':
+			if err := lx.quoted(r); err != nil {
+				return lx.statements, err
+			}
+		case r == '\n' || r == ';':
+			lx.advance()
+			lx.endStatement()
+		case r == ' ' || r == '\t' || r == '\r':
+			lx.advance()
+			lx.endWord()
+			lx.writeRaw(r)
+		case r == '#' && !lx.inWord:
+			lx.comment()
+		case r == '/' && !lx.inWord && lx.current == nil && lx.nextIs(1, '/'):
+			lx.comment()
+		default:
+			lx.startWord()
+			lx.writeRaw(r)
+			lx.word.WriteRune(lx.advance())
+		}
+	}
+
+	lx.endStatement()
+	return lx.statements, nil
+}
+
+func (lx *scriptLexer) nextIs(offset int, want rune) bool {
+	r, ok := lx.peek(offset)
+	return ok && r == want
+}
+
+// continuation consume '\' seguido de salto de línea. La continuación
+// desaparece sin cortar la palabra, como en sh: los espacios que haya antes
+// o después son los que separan.
+func (lx *scriptLexer) continuation() bool {
+	switch {
+	case lx.nextIs(1, '\n'):
+		lx.advance()
+		lx.advance()
+	case lx.nextIs(1, '\r') && lx.nextIs(2, '\n'):
+		lx.advance()
+		lx.advance()
+		lx.advance()
+	default:
+		return false
+	}
+	return true
+}
+
+func (lx *scriptLexer) quoted(quote rune) error {
+	line, col := lx.line, lx.column
+
+	lx.startWord()
+	lx.writeRaw(lx.advance())
+
+	for {
+		r, ok := lx.peek(0)
+		if !ok || r == '\n' {
+			return &ScriptSyntaxError{
+				Line:    line,
+				Column:  col,
+				Message: fmt.Sprintf("comilla %c sin cerrar", quote),
+			}
+		}
+
+		if r == quote {
+			lx.writeRaw(lx.advance())
+			return nil
+		}
+
+		if r == '"' {
+			return &ScriptSyntaxError{
+				Line:    lx.line,
+				Column:  lx.column,
+				Message: "un valor no puede contener comillas dobles",
+			}
+		}
+
+		lx.writeRaw(r)
+		lx.word.WriteRune(lx.advance())
+	}
+}
+
+func (lx *scriptLexer) comment() {
+	lx.endWord()
+
+	if r, _ := lx.peek(0); r == '/' {
+		lx.advance()
+	}
+	lx.advance()
+
+	var text strings.Builder
+	for lx.pos < len(lx.input) {
+		if r, _ := lx.peek(0); r == '\n' {
+			break
+		}
+		text.WriteRune(lx.advance())
+	}
+
+	if lx.current != nil {
+		lx.current.Comment = strings.TrimSpace(text.String())
+	}
+}
+
+func (lx *scriptLexer) startWord() {
+	if lx.current == nil {
+		lx.current = &ScriptStatement{Line: lx.line, Column: lx.column}
+		lx.raw.Reset()
+	}
+	if !lx.inWord {
+		lx.inWord = true
+		lx.wordLine = lx.line
+		lx.wordCol = lx.column
+		lx.word.Reset()
+	}
+}
+
+func (lx *scriptLexer) writeRaw(r rune) {
+	if lx.current != nil {
+		lx.raw.WriteRune(r)
+	}
+}
+
+func (lx *scriptLexer) endWord() {
+	if !lx.inWord {
+		return
+	}
+
+	lx.current.Tokens = append(lx.current.Tokens, ScriptToken{
+		Text:   lx.word.String(),
+		Line:   lx.wordLine,
+		Column: lx.wordCol,
+	})
+	lx.inWord = false
+}
+
+func (lx *scriptLexer) endStatement() {
+	lx.endWord()
+
+	if lx.current == nil {
+		return
+	}
+
+	statement := *lx.current
+	statement.Raw = strings.TrimSpace(lx.raw.String())
+	statement.Command = joinTokens(statement.Tokens)
+	if len(statement.Tokens) > 0 {
+		lx.statements = append(lx.statements, statement)
+	}
+
+	lx.current = nil
+	lx.raw.Reset()
+}
+
+// joinTokens vuelve a armar el comando para Analyzer, poniendo entre
+// comillas dobles los valores que tienen espacios o caracteres especiales.
+// Para tokens de LexScript el resultado vuelve a separarse con LexCommand en
+// los mismos tokens; uno con '"' no se puede representar y el resultado no
+// coincide, cosa que runTypedCommand comprueba.
+func joinTokens(tokens []ScriptToken) string {
+	parts := make([]string, len(tokens))
+
+	for i, token := range tokens {
+		text := token.Text
+		if !needsQuotes(text) {
+			parts[i] = text
+			continue
+		}
+
+		if strings.HasPrefix(text, "-") {
+			if idx := strings.Index(text, "="); idx >= 0 && !needsQuotes(text[:idx]) {
+				parts[i] = text[:idx+1] + quoteToken(text[idx+1:])
+				continue
+			}
+		}
+		parts[i] = quoteToken(text)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func needsQuotes(text string) bool {
+	return text == "" || strings.HasPrefix(text, "//") || strings.HasSuffix(text, "\\") ||
+		strings.ContainsAny(text, " \t\r;#'\"")
+}
+
+func quoteToken(text string) string {
+	return `"` + text + `"`
+}
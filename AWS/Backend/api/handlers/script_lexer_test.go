@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func tokenTexts(statement ScriptStatement) []string {
+	texts := make([]string, len(statement.Tokens))
+	for i, token := range statement.Tokens {
+		texts[i] = token.Text
+	}
+	return texts
+}
+
+func TestLexScript(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		commands [][]string
+		comments []string
+	}{
+		{
+			name:     "vacío",
+			input:    "  \n\n ; ;",
+			commands: [][]string{},
+		},
+		{
+			name:     "separadores",
+			input:    "mkdisk -size=5\nrmdisk -driveletter=A; mount -driveletter=A -name=P1",
+			commands: [][]string{{"mkdisk", "-size=5"}, {"rmdisk", "-driveletter=A"}, {"mount", "-driveletter=A", "-name=P1"}},
+		},
+		{
+			name:     "comillas dobles y simples",
+			input:    `mkdir -path="/home/mis docs" -r 'otro valor'`,
+			commands: [][]string{{"mkdir", "-path=/home/mis docs", "-r", "otro valor"}},
+		},
+		{
+			name:     "punto y coma dentro de comillas",
+			input:    `mkfile -path="/a;b"`,
+			commands: [][]string{{"mkfile", "-path=/a;b"}},
+		},
+		{
+			name:     "barras invertidas literales",
+			input:    `mkdisk -path=C:\discos\a.dsk -cont="x\y" 'a\b' /a\ b`,
+			commands: [][]string{{"mkdisk", `-path=C:\discos\a.dsk`, `-cont=x\y`, `a\b`, `/a\`, "b"}},
+		},
+		{
+			name:     "barra al final del script",
+			input:    `mkdir -path=C:\dir\`,
+			commands: [][]string{{"mkdir", `-path=C:\dir\`}},
+		},
+		{
+			name:     "barra al final entre comillas",
+			input:    "mkdir -path=\"C:\\dir\\\"\nlogout",
+			commands: [][]string{{"mkdir", `-path=C:\dir\`}, {"logout"}},
+		},
+		{
+			name:     "comentarios",
+			input:    "# encabezado\n// otro\nmkdisk -size=5 # disco A\nrmdisk -driveletter=A #@onerror=continue",
+			commands: [][]string{{"mkdisk", "-size=5"}, {"rmdisk", "-driveletter=A"}},
+			comments: []string{"disco A", "@onerror=continue"},
+		},
+		{
+			name:     "numeral dentro de una palabra",
+			input:    "mkdir -path=/a#b",
+			commands: [][]string{{"mkdir", "-path=/a#b"}},
+		},
+		{
+			name:     "continuación entre palabras",
+			input:    "mkdisk -size=5 \\\n  -unit=M",
+			commands: [][]string{{"mkdisk", "-size=5", "-unit=M"}},
+		},
+		{
+			name:     "continuación dentro de una palabra",
+			input:    "mkdisk -si\\\nze=5 -unit=M",
+			commands: [][]string{{"mkdisk", "-size=5", "-unit=M"}},
+		},
+		{
+			name:     "continuación con CRLF",
+			input:    "mkdisk -size=5\\\r\n -unit=M",
+			commands: [][]string{{"mkdisk", "-size=5", "-unit=M"}},
+		},
+		{
+			name:     "comillas vacías",
+			input:    `mkfile -path="" ""`,
+			commands: [][]string{{"mkfile", "-path=", ""}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements, err := LexScript(tt.input)
+			if err != nil {
+				t.Fatalf("LexScript(%q): %v", tt.input, err)
+			}
+
+			got := [][]string{}
+			var comments []string
+			for _, statement := range statements {
+				got = append(got, tokenTexts(statement))
+				if statement.Comment != "" {
+					comments = append(comments, statement.Comment)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.commands) {
+				t.Errorf("tokens = %q, se esperaba %q", got, tt.commands)
+			}
+			if !reflect.DeepEqual(comments, tt.comments) {
+				t.Errorf("comentarios = %q, se esperaba %q", comments, tt.comments)
+			}
+		})
+	}
+}
+
+func TestLexScriptPositions(t *testing.T) {
+	statements, err := LexScript("mkdisk -size=5\n  rmdisk  -driveletter=A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statements) != 2 {
+		t.Fatalf("se esperaban 2 comandos, hay %d", len(statements))
+	}
+
+	second := statements[1]
+	if second.Line != 2 || second.Column != 3 {
+		t.Errorf("comando en %d:%d, se esperaba 2:3", second.Line, second.Column)
+	}
+	if token := second.Tokens[1]; token.Line != 2 || token.Column != 11 {
+		t.Errorf("token en %d:%d, se esperaba 2:11", token.Line, token.Column)
+	}
+	if second.Raw != "rmdisk  -driveletter=A" {
+		t.Errorf("Raw = %q", second.Raw)
+	}
+}
+
+func TestLexScriptErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		line   int
+		column int
+	}{
+		{name: "comilla sin cerrar", input: `mkdir -path="/a`, line: 1, column: 13},
+		{name: "comilla cortada por salto", input: "mkdir\nmkfile -path='/a\n'", line: 2, column: 14},
+		{name: "comilla doble entre simples", input: `mkfile -cont='a"b'`, line: 1, column: 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LexScript(tt.input)
+
+			var syntaxErr *ScriptSyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("se esperaba ScriptSyntaxError, se obtuvo %v", err)
+			}
+			if syntaxErr.Line != tt.line || syntaxErr.Column != tt.column {
+				t.Errorf("error en %d:%d, se esperaba %d:%d", syntaxErr.Line, syntaxErr.Column, tt.line, tt.column)
+			}
+		})
+	}
+}
+
+func TestLexCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		tokens  []string
+		wantErr bool
+	}{
+		{name: "vacío", input: "  # nada", tokens: []string{}},
+		{name: "un comando", input: "login -user=root -pass=123 -id=A1", tokens: []string{"login", "-user=root", "-pass=123", "-id=A1"}},
+		{name: "varios comandos", input: "logout; logout", wantErr: true},
+		{name: "varias líneas", input: "logout\nlogout", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statement, err := LexCommand(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("se esperaba error para %q", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tokenTexts(statement); !reflect.DeepEqual(got, tt.tokens) {
+				t.Errorf("tokens = %q, se esperaba %q", got, tt.tokens)
+			}
+		})
+	}
+}
+
+func TestJoinTokensRoundTrip(t *testing.T) {
+	tests := []struct {
+		tokens []string
+		want   string
+	}{
+		{tokens: []string{"mkdisk", "-size=5", "-unit=M"}, want: "mkdisk -size=5 -unit=M"},
+		{tokens: []string{"mkdir", "-path=/mis docs"}, want: `mkdir -path="/mis docs"`},
+		{tokens: []string{"mkfile", `-cont=a\b`}, want: `mkfile -cont=a\b`},
+		{tokens: []string{"mkdir", `-path=C:\mis discos\`}, want: `mkdir -path="C:\mis discos\"`},
+		{tokens: []string{"mkfile", `-path=/x`, `\`}, want: `mkfile -path=/x "\"`},
+		{tokens: []string{"mkfile", "-path=/a;b#c'd"}, want: `mkfile -path="/a;b#c'd"`},
+		{tokens: []string{"//x", ""}, want: `"//x" ""`},
+		{tokens: []string{"-a b=c"}, want: `"-a b=c"`},
+	}
+
+	for _, tt := range tests {
+		tokens := make([]ScriptToken, len(tt.tokens))
+		for i, text := range tt.tokens {
+			tokens[i] = ScriptToken{Text: text}
+		}
+
+		joined := joinTokens(tokens)
+		if joined != tt.want {
+			t.Errorf("joinTokens(%q) = %s, se esperaba %s", tt.tokens, joined, tt.want)
+		}
+
+		statement, err := LexCommand(joined)
+		if err != nil {
+			t.Errorf("LexCommand(%s): %v", joined, err)
+			continue
+		}
+		if got := tokenTexts(statement); !reflect.DeepEqual(got, tt.tokens) {
+			t.Errorf("LexCommand(%s) = %q, se esperaba %q", joined, got, tt.tokens)
+		}
+		if statement.Command != joined {
+			t.Errorf("Command = %s, se esperaba %s", statement.Command, joined)
+		}
+	}
+}
+
+// Un valor con comillas dobles no tiene forma de llegar al núcleo: el
+// comando armado no vuelve a dar los mismos tokens y runTypedCommand lo
+// rechaza.
+func TestJoinTokensDoubleQuote(t *testing.T) {
+	joined := joinTokens([]ScriptToken{{Text: "mkfile"}, {Text: `-cont=a"b`}})
+
+	statement, err := LexCommand(joined)
+	if err == nil && statement.Command == joined {
+		t.Errorf("%s se aceptó como %q", joined, tokenTexts(statement))
+	}
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"Backend/api/handlers/usermanag"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type DiagnosticSeverity string
+
+const (
+	SeverityError   DiagnosticSeverity = "error"
+	SeverityWarning DiagnosticSeverity = "warning"
+)
+
+type ScriptDiagnostic struct {
+	Line     int                `json:"line"`
+	Column   int                `json:"column"`
+	Severity DiagnosticSeverity `json:"severity"`
+	Code     ErrorCode          `json:"code,omitempty"`
+	Command  string             `json:"command,omitempty"`
+	Message  string             `json:"message"`
+}
+
+type ScriptValidationResult struct {
+	Valid         bool               `json:"valid"`
+	Source        string             `json:"source"`
+	TotalCommands int                `json:"total_commands"`
+	Errors        int                `json:"errors"`
+	Warnings      int                `json:"warnings"`
+	Diagnostics   []ScriptDiagnostic `json:"diagnostics"`
+}
+
+type ScriptRequest struct {
+	Command string   `json:"command"`
+	Script  string   `json:"script"`
+	OnError string   `json:"on_error,omitempty"`
+	StopOn  []string `json:"stop_on,omitempty"`
+}
+
+// scriptInput es el script ya leído, venga del JSON o de un archivo.
+type scriptInput struct {
+	ScriptRequest
+	Source   string
+	FromFile bool
+}
+
+// Policy aplica la misma política por defecto que execute o los comandos
+// separados por ';' según de dónde venga el script.
+func (in scriptInput) Policy() (ErrorPolicy, error) {
+	fallback := defaultStringPolicy
+	if in.FromFile {
+		fallback = defaultFilePolicy
+	}
+	return ParseErrorPolicy(in.OnError, in.StopOn, fallback)
+}
+
+const maxScriptUploadSize = 10 << 20
+
+// ValidateScript revisa un script sin ejecutarlo. Acepta el mismo JSON que
+// los trabajos ('script' o un comando execute) o un archivo subido en el
+// campo 'file' de un formulario multipart.
+func ValidateScript(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	input, err := readScriptInput(r)
+	if err != nil {
+		http.Error(w, err.Error(), scriptErrorStatus(err))
+		return
+	}
+
+	result := validateScript(input.Script)
+	result.Source = input.Source
+
+	json.NewEncoder(w).Encode(result)
+}
+
+func readScriptInput(r *http.Request) (scriptInput, error) {
+	var input scriptInput
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxScriptUploadSize); err != nil {
+			return input, fmt.Errorf("formulario inválido: %v", err)
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return input, fmt.Errorf("se requiere el archivo en el campo 'file'")
+		}
+		defer file.Close()
+
+		content, err := io.ReadAll(file)
+		if err != nil {
+			return input, fmt.Errorf("no se pudo leer el archivo: %v", err)
+		}
+
+		input.Script = string(content)
+		input.Source = header.Filename
+		input.FromFile = true
+		input.OnError = r.FormValue("on_error")
+		if stopOn := r.FormValue("stop_on"); stopOn != "" {
+			input.StopOn = strings.Split(stopOn, ",")
+		}
+		return input, nil
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input.ScriptRequest); err != nil {
+		return input, fmt.Errorf("Request JSON inválido")
+	}
+
+	switch {
+	case strings.TrimSpace(input.Script) != "":
+		input.Source = "script"
+	case IsBatchCommand(input.Command):
+		path, _, _ := ParseExecuteCommand(input.Command)
+		content, err := readScriptFile(usermanag.CallerFromRequest(nil, r), path)
+		if err != nil {
+			return input, err
+		}
+		input.Script = content
+		input.Source = path
+		input.FromFile = true
+	default:
+		return input, fmt.Errorf("Se requiere 'script', un comando execute con -path o un archivo")
+	}
+
+	return input, nil
+}
+
+// validateScript revisa cada comando contra commandSpecs. Si el script
+// tiene un error de sintaxis se informa y se validan los comandos anteriores.
+func validateScript(script string) *ScriptValidationResult {
+	result := &ScriptValidationResult{Diagnostics: []ScriptDiagnostic{}}
+
+	statements, err := LexScript(script)
+	for _, statement := range statements {
+		result.Diagnostics = append(result.Diagnostics, validateStatement(statement)...)
+	}
+	result.TotalCommands = len(statements)
+
+	if syntaxErr, ok := err.(*ScriptSyntaxError); ok {
+		result.Diagnostics = append(result.Diagnostics, ScriptDiagnostic{
+			Line:     syntaxErr.Line,
+			Column:   syntaxErr.Column,
+			Severity: SeverityError,
+			Code:     ErrSyntax,
+			Message:  syntaxErr.Message,
+		})
+	}
+
+	for _, diagnostic := range result.Diagnostics {
+		if diagnostic.Severity == SeverityError {
+			result.Errors++
+		} else {
+			result.Warnings++
+		}
+	}
+	result.Valid = result.Errors == 0
+
+	return result
+}
+
+func validateStatement(statement ScriptStatement) []ScriptDiagnostic {
+	var diagnostics []ScriptDiagnostic
+
+	report := func(token ScriptToken, severity DiagnosticSeverity, code ErrorCode, format string, args ...interface{}) {
+		diagnostics = append(diagnostics, ScriptDiagnostic{
+			Line:     token.Line,
+			Column:   token.Column,
+			Severity: severity,
+			Code:     code,
+			Command:  statement.Command,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	nameToken := statement.Tokens[0]
+	name := strings.ToLower(nameToken.Text)
+
+	if _, err := policyDirective(statement.Comment); err != nil {
+		report(nameToken, SeverityError, ErrInvalidParameters, "%v", err)
+	}
+
+	spec, known := commandSpecs[name]
+	if !known {
+		report(nameToken, SeverityError, ErrInvalidCommand, "comando desconocido: %s", nameToken.Text)
+		return diagnostics
+	}
+
+	seen := make(map[string]ScriptToken)
+
+	for _, token := range statement.Tokens[1:] {
+		if !strings.HasPrefix(token.Text, "-") {
+			report(token, SeverityWarning, ErrInvalidParameters, "'%s' no es un parámetro y será ignorado", token.Text)
+			continue
+		}
+
+		key, value, hasValue := strings.Cut(token.Text[1:], "=")
+		key = strings.ToLower(key)
+
+		if _, dup := seen[key]; dup {
+			report(token, SeverityWarning, ErrInvalidParameters, "parámetro -%s repetido, se usa el último valor", key)
+		}
+		seen[key] = token
+
+		switch {
+		case containsParam(spec.Flags, key):
+			if hasValue {
+				report(token, SeverityError, ErrInvalidParameters, "-%s no lleva valor", key)
+			}
+		case containsParam(spec.Required, key) || containsParam(spec.Optional, key) || isCatFileParam(name, key):
+			if !hasValue || value == "" {
+				report(token, SeverityError, ErrInvalidParameters, "-%s requiere un valor", key)
+				continue
+			}
+			if message := checkParamValue(spec, key, value); message != "" {
+				report(token, SeverityError, ErrInvalidParameters, "%s", message)
+			}
+		default:
+			report(token, SeverityError, ErrInvalidParameters, "parámetro desconocido -%s para %s", key, name)
+		}
+	}
+
+	for _, param := range spec.Required {
+		if _, ok := seen[param]; !ok {
+			report(nameToken, SeverityError, ErrInvalidParameters, "falta el parámetro -%s", param)
+		}
+	}
+
+	if name == "fdisk" {
+		_, hasSize := seen["size"]
+		_, hasDelete := seen["delete"]
+		_, hasAdd := seen["add"]
+		if !hasSize && !hasDelete && !hasAdd {
+			report(nameToken, SeverityError, ErrInvalidParameters, "fdisk requiere -size para crear una partición")
+		}
+		if hasDelete && hasAdd {
+			report(nameToken, SeverityError, ErrInvalidParameters, "-delete y -add no pueden usarse juntos")
+		}
+	}
+
+	if name == "execute" {
+		report(nameToken, SeverityWarning, "", "execute dentro de un script no se valida de forma recursiva")
+	}
+
+	return diagnostics
+}
+
+func checkParamValue(spec commandSpec, key, value string) string {
+	if allowed, ok := spec.Values[key]; ok && !containsParam(allowed, strings.ToUpper(value)) {
+		return fmt.Sprintf("valor inválido para -%s: %s (se acepta %s)", key, value, strings.Join(allowed, ", "))
+	}
+
+	switch key {
+	case "size":
+		if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+			return fmt.Sprintf("-size debe ser un entero positivo: %s", value)
+		}
+	case "add":
+		if n, err := strconv.Atoi(value); err != nil || n == 0 {
+			return fmt.Sprintf("-add debe ser un entero distinto de cero: %s", value)
+		}
+	case "driveletter":
+		if len(value) != 1 || !strings.ContainsAny(strings.ToUpper(value), "ABCDEFGHIJKLMNOPQRSTUVWXYZ") {
+			return fmt.Sprintf("-driveletter debe ser una sola letra: %s", value)
+		}
+	}
+
+	return ""
+}
+
+func containsParam(list []string, name string) bool {
+	for _, item := range list {
+		if item == name {
+			return true
+		}
+	}
+	return false
+}
+
+// cat acepta -file1, -file2, ... -fileN.
+func isCatFileParam(command, key string) bool {
+	if command != "cat" || !strings.HasPrefix(key, "file") {
+		return false
+	}
+	n, err := strconv.Atoi(key[len("file"):])
+	return err == nil && n > 0
+}
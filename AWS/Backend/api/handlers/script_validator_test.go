@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+type wantDiagnostic struct {
+	line     int
+	severity DiagnosticSeverity
+	code     ErrorCode
+	contains string
+}
+
+func TestValidateScript(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		total  int
+		want   []wantDiagnostic
+	}{
+		{
+			name:   "script válido",
+			script: "mkdisk -size=5 -unit=M\nfdisk -driveletter=A -name=P1 -size=1 -type=P\nmount -driveletter=A -name=P1\nmkfs -id=A1 -fs=3fs",
+			total:  4,
+		},
+		{
+			name:   "comando desconocido",
+			script: "mkdisk -size=5\nformat -id=A1",
+			total:  2,
+			want:   []wantDiagnostic{{line: 2, severity: SeverityError, code: ErrInvalidCommand}},
+		},
+		{
+			name:   "falta parámetro obligatorio",
+			script: "mount -driveletter=A",
+			total:  1,
+			want:   []wantDiagnostic{{line: 1, severity: SeverityError, code: ErrInvalidParameters, contains: "-name"}},
+		},
+		{
+			name:   "valores inválidos",
+			script: "mkdisk -size=0 -unit=G\nrmdisk -driveletter=AB",
+			total:  2,
+			want: []wantDiagnostic{
+				{line: 1, severity: SeverityError, code: ErrInvalidParameters, contains: "-size"},
+				{line: 1, severity: SeverityError, code: ErrInvalidParameters, contains: "-unit"},
+				{line: 2, severity: SeverityError, code: ErrInvalidParameters, contains: "-driveletter"},
+			},
+		},
+		{
+			name:   "parámetro desconocido y repetido",
+			script: "mkdisk -size=5 -size=6 -color=red",
+			total:  1,
+			want: []wantDiagnostic{
+				{line: 1, severity: SeverityWarning, code: ErrInvalidParameters, contains: "repetido"},
+				{line: 1, severity: SeverityError, code: ErrInvalidParameters, contains: "-color"},
+			},
+		},
+		{
+			name:   "bandera con valor y palabra suelta",
+			script: "mkdir -path=/a -r=true extra",
+			total:  1,
+			want: []wantDiagnostic{
+				{line: 1, severity: SeverityError, code: ErrInvalidParameters, contains: "-r no lleva valor"},
+				{line: 1, severity: SeverityWarning, code: ErrInvalidParameters, contains: "extra"},
+			},
+		},
+		{
+			name:   "fdisk sin size ni delete",
+			script: "fdisk -driveletter=A -name=P1\nfdisk -driveletter=A -name=P1 -delete=full -add=5",
+			total:  2,
+			want: []wantDiagnostic{
+				{line: 1, severity: SeverityError, code: ErrInvalidParameters, contains: "-size"},
+				{line: 2, severity: SeverityError, code: ErrInvalidParameters, contains: "-delete y -add"},
+			},
+		},
+		{
+			name:   "cat con varios archivos",
+			script: "cat -file1=/a.txt -file2=/b.txt\ncat -file1=/a.txt -file0=/b.txt",
+			total:  2,
+			want:   []wantDiagnostic{{line: 2, severity: SeverityError, code: ErrInvalidParameters, contains: "-file0"}},
+		},
+		{
+			name:   "directiva inválida",
+			script: "rmdisk -driveletter=A #@onerror=maybe",
+			total:  1,
+			want:   []wantDiagnostic{{line: 1, severity: SeverityError, code: ErrInvalidParameters}},
+		},
+		{
+			name:   "execute anidado",
+			script: "execute -path=/tmp/otro.smia",
+			total:  1,
+			want:   []wantDiagnostic{{line: 1, severity: SeverityWarning}},
+		},
+		{
+			name:   "error de sintaxis tras comandos válidos",
+			script: "mkdisk -size=5\nmkdir -path=\"/sin cerrar",
+			total:  1,
+			want:   []wantDiagnostic{{line: 2, severity: SeverityError, code: ErrSyntax}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validateScript(tt.script)
+
+			if result.TotalCommands != tt.total {
+				t.Errorf("TotalCommands = %d, se esperaba %d", result.TotalCommands, tt.total)
+			}
+			if len(result.Diagnostics) != len(tt.want) {
+				t.Fatalf("diagnósticos = %+v, se esperaban %d", result.Diagnostics, len(tt.want))
+			}
+
+			errCount := 0
+			for i, want := range tt.want {
+				got := result.Diagnostics[i]
+				if got.Line != want.line || got.Severity != want.severity || got.Code != want.code {
+					t.Errorf("diagnóstico %d = %+v, se esperaba línea %d, %s, %q", i, got, want.line, want.severity, want.code)
+				}
+				if !strings.Contains(got.Message, want.contains) {
+					t.Errorf("diagnóstico %d: %q no contiene %q", i, got.Message, want.contains)
+				}
+				if want.severity == SeverityError {
+					errCount++
+				}
+			}
+
+			if result.Errors != errCount || result.Warnings != len(tt.want)-errCount {
+				t.Errorf("errores/avisos = %d/%d, se esperaba %d/%d", result.Errors, result.Warnings, errCount, len(tt.want)-errCount)
+			}
+			if result.Valid != (errCount == 0) {
+				t.Errorf("Valid = %t", result.Valid)
+			}
+		})
+	}
+}
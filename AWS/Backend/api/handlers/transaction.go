@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"Backend/DiskManagement"
+	"Backend/Utils"
+	"Backend/api/handlers/console"
+	"Backend/api/handlers/usermanag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	TxCommitted      = "committed"
+	TxRolledBack     = "rolled_back"
+	TxRollbackFailed = "rollback_failed"
+	TxNotStarted     = "not_started"
+)
+
+// diskTransaction guarda una copia de los discos que toca un lote y de la
+// tabla de montajes para poder restaurarlos si el lote no termina bien.
+// Requiere coreMu tomado en escritura durante todo el lote.
+type diskTransaction struct {
+	dir      string
+	backup   string
+	existing map[string]bool
+	saved    []string
+	mounts   map[string][]DiskManagement.MountedPartition
+}
+
+func beginDiskTransaction(caller *usermanag.Caller, lines []batchLine) (*diskTransaction, error) {
+	return beginDiskTransactionIn(Utils.GetDiskDirectory(), caller, lines)
+}
+
+func beginDiskTransactionIn(dir string, caller *usermanag.Caller, lines []batchLine) (*diskTransaction, error) {
+	names, err := listDiskFiles(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("no se pudo leer %s: %v", dir, err)
+	}
+
+	existing := make(map[string]bool, len(names))
+	for _, name := range names {
+		existing[name] = true
+	}
+
+	mounts := cloneMountTable(DiskManagement.GetMountedPartitions())
+
+	var saved []string
+	for _, name := range touchedDisks(caller, lines, mounts, names) {
+		if existing[name] {
+			saved = append(saved, name)
+		}
+	}
+
+	backup, err := os.MkdirTemp("", "mia-tx-")
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo crear directorio temporal: %v", err)
+	}
+
+	if err := copyDiskFiles(dir, backup, saved); err != nil {
+		os.RemoveAll(backup)
+		return nil, err
+	}
+
+	console.Printf("Transacción iniciada: %d discos respaldados en %s\n", len(saved), backup)
+
+	return &diskTransaction{
+		dir:      dir,
+		backup:   backup,
+		existing: existing,
+		saved:    saved,
+		mounts:   mounts,
+	}, nil
+}
+
+func (tx *diskTransaction) commit() {
+	os.RemoveAll(tx.backup)
+	console.Printf("Transacción confirmada\n")
+}
+
+// rollback devuelve los discos respaldados, borra los creados por el lote y
+// restaura la tabla de montajes. El respaldo solo se borra si todo salió bien.
+func (tx *diskTransaction) rollback() error {
+	var failures []string
+
+	if err := copyDiskFiles(tx.backup, tx.dir, tx.saved); err != nil {
+		failures = append(failures, err.Error())
+	}
+
+	if names, err := listDiskFiles(tx.dir); err == nil {
+		for _, name := range names {
+			if tx.existing[name] {
+				continue
+			}
+			if err := os.Remove(filepath.Join(tx.dir, name)); err != nil {
+				failures = append(failures, fmt.Sprintf("no se pudo borrar %s: %v", name, err))
+			}
+		}
+	}
+
+	if err := replaceMountTable(tx.mounts); err != nil {
+		failures = append(failures, err.Error())
+	}
+
+	if len(failures) > 0 {
+		console.Printf("Rollback incompleto, respaldo conservado en %s\n", tx.backup)
+		return fmt.Errorf("%s (respaldo en %s)", strings.Join(failures, "; "), tx.backup)
+	}
+
+	os.RemoveAll(tx.backup)
+	console.Printf("Transacción revertida: %d discos restaurados\n", len(tx.saved))
+	return nil
+}
+
+// touchedDisks deduce qué discos puede modificar el script. Si algún comando
+// no permite saberlo (execute, un id montado dentro del propio script) se
+// respaldan todos.
+func touchedDisks(caller *usermanag.Caller, lines []batchLine, mounts map[string][]DiskManagement.MountedPartition, all []string) []string {
+	diskOf := make(map[string]string)
+	for _, partitions := range mounts {
+		for _, part := range partitions {
+			diskOf[strings.Trim(string(part.ID), "\x00")] = filepath.Base(part.Path)
+		}
+	}
+
+	sessionID := ""
+	if session, ok := caller.Session(); ok {
+		sessionID = session.PartitionID
+	}
+
+	touched := make(map[string]bool)
+	resolve := func(id string) bool {
+		name, ok := diskOf[id]
+		if ok {
+			touched[name] = true
+		}
+		return ok
+	}
+
+	for _, line := range lines {
+		cmd := parseCommandLine(line.Command)
+		spec, known := commandSpecs[cmd.Name]
+
+		switch {
+		case !known || cmd.Name == "execute":
+			return all
+		case cmd.Has("driveletter"):
+			touched[strings.ToUpper(cmd.Param("driveletter"))+".dsk"] = true
+		case cmd.Has("id"):
+			if !resolve(cmd.Param("id")) {
+				return all
+			}
+			if cmd.Name == "login" {
+				sessionID = cmd.Param("id")
+			}
+		case spec.NeedsSession && spec.Effect == effectDisk:
+			if !resolve(sessionID) {
+				return all
+			}
+		}
+	}
+
+	names := make([]string, 0, len(touched))
+	for name := range touched {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"Backend/api/handlers/usermanag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeDisk(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readDisk(t *testing.T, dir, name string) (string, bool) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return "", false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), true
+}
+
+func anonymousCaller() *usermanag.Caller {
+	return usermanag.CallerFromRequest(nil, httptest.NewRequest(http.MethodPost, "/", nil))
+}
+
+func batchLines(commands ...string) []batchLine {
+	lines := make([]batchLine, len(commands))
+	for i, command := range commands {
+		lines[i] = batchLine{Number: i + 1, Command: command}
+	}
+	return lines
+}
+
+func TestDiskTransactionRollback(t *testing.T) {
+	dir := t.TempDir()
+	writeDisk(t, dir, "A.dsk", "original A")
+	writeDisk(t, dir, "B.dsk", "original B")
+
+	lines := batchLines("fdisk -driveletter=A -name=P1 -size=1", "mkdisk -size=5")
+	tx, err := beginDiskTransactionIn(dir, anonymousCaller(), lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tx.saved, []string{"A.dsk"}) {
+		t.Fatalf("respaldados = %v, se esperaba [A.dsk]", tx.saved)
+	}
+
+	// Lo que haría el lote: cambia A, crea C y (fuera de lo previsto) B.
+	writeDisk(t, dir, "A.dsk", "modificado")
+	writeDisk(t, dir, "B.dsk", "modificado")
+	writeDisk(t, dir, "C.dsk", "nuevo")
+
+	if err := tx.rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	if content, _ := readDisk(t, dir, "A.dsk"); content != "original A" {
+		t.Errorf("A.dsk = %q, no se restauró", content)
+	}
+	if _, exists := readDisk(t, dir, "C.dsk"); exists {
+		t.Error("C.dsk, creado por el lote, sigue existiendo")
+	}
+	if content, _ := readDisk(t, dir, "B.dsk"); content != "modificado" {
+		t.Errorf("B.dsk = %q; no estaba respaldado y no debía tocarse", content)
+	}
+	if _, err := os.Stat(tx.backup); !os.IsNotExist(err) {
+		t.Errorf("el respaldo %s no se borró", tx.backup)
+	}
+}
+
+func TestDiskTransactionCommit(t *testing.T) {
+	dir := t.TempDir()
+	writeDisk(t, dir, "A.dsk", "original")
+
+	tx, err := beginDiskTransactionIn(dir, anonymousCaller(), batchLines("rmdisk -driveletter=a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeDisk(t, dir, "A.dsk", "modificado")
+	tx.commit()
+
+	if content, _ := readDisk(t, dir, "A.dsk"); content != "modificado" {
+		t.Errorf("A.dsk = %q tras confirmar", content)
+	}
+	if _, err := os.Stat(tx.backup); !os.IsNotExist(err) {
+		t.Errorf("el respaldo %s no se borró", tx.backup)
+	}
+}
+
+// Si no se puede restaurar un disco, el rollback lo informa y conserva el
+// respaldo para recuperarlo a mano.
+func TestDiskTransactionRollbackFailure(t *testing.T) {
+	dir := t.TempDir()
+	writeDisk(t, dir, "A.dsk", "original")
+
+	tx, err := beginDiskTransactionIn(dir, anonymousCaller(), batchLines("fdisk -driveletter=A -name=P1 -size=1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tx.backup)
+
+	if err := os.Remove(filepath.Join(tx.backup, "A.dsk")); err != nil {
+		t.Fatal(err)
+	}
+	writeDisk(t, dir, "C.dsk", "nuevo")
+
+	if err := tx.rollback(); err == nil {
+		t.Fatal("el rollback no informó el error")
+	}
+	if _, err := os.Stat(tx.backup); err != nil {
+		t.Errorf("se borró el respaldo tras un rollback incompleto: %v", err)
+	}
+	if _, exists := readDisk(t, dir, "C.dsk"); exists {
+		t.Error("el rollback se detuvo en el primer error sin borrar C.dsk")
+	}
+}
+
+func TestTouchedDisks(t *testing.T) {
+	all := []string{"A.dsk", "B.dsk", "C.dsk"}
+
+	tests := []struct {
+		name     string
+		commands []string
+		want     []string
+	}{
+		{name: "por letra", commands: []string{"fdisk -driveletter=b -name=P1 -size=1", "rmdisk -driveletter=A"}, want: []string{"A.dsk", "B.dsk"}},
+		{name: "solo lectura", commands: []string{"mounted", "cat -file1=/a.txt"}, want: []string{}},
+		{name: "execute", commands: []string{"rmdisk -driveletter=A", "execute -path=a.mia"}, want: all},
+		{name: "comando desconocido", commands: []string{"formatear -id=A1"}, want: all},
+		{name: "id sin montar", commands: []string{"mkfs -id=A1"}, want: all},
+		{name: "escritura sin sesión", commands: []string{"mkdir -path=/docs"}, want: all},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := touchedDisks(anonymousCaller(), batchLines(tt.commands...), nil, all)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("touchedDisks = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"Backend/api/handlers/usermanag"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Los endpoints REST que modifican discos arman el comando equivalente y lo
+// ejecutan igual que la consola, para que el comportamiento sea idéntico.
+
+type APIError struct {
+	Success     bool               `json:"success"`
+	Error       string             `json:"error"`
+	Code        ErrorCode          `json:"code"`
+	Command     string             `json:"command,omitempty"`
+	Output      string             `json:"output,omitempty"`
+	Diagnostics []ScriptDiagnostic `json:"diagnostics,omitempty"`
+}
+
+func writeAPIError(w http.ResponseWriter, status int, apiErr APIError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiErr)
+}
+
+func httpStatusForCode(code ErrorCode) int {
+	switch code {
+	case ErrInvalidCommand, ErrSyntax, ErrInvalidParameters:
+		return http.StatusBadRequest
+	case ErrNoSession:
+		return http.StatusUnauthorized
+	case ErrPermissionDenied:
+		return http.StatusForbidden
+	case ErrNotFound:
+		return http.StatusNotFound
+	case ErrSessionActive, ErrNotMounted, ErrAlreadyMounted,
+		ErrNoSpace, ErrPartitionLimit, ErrDuplicateName, ErrExtendedExists, ErrNoExtended,
+		ErrTargetExists, ErrNotEmpty, ErrNoFreeInodes, ErrNoFreeBlocks, ErrUnsupportedFS:
+		return http.StatusConflict
+	case ErrInternal:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusUnprocessableEntity
+	}
+}
+
+// commandLine arma un comando a partir de pares parámetro/valor; los valores
+// vacíos se omiten.
+func commandLine(name string, params ...string) string {
+	tokens := []ScriptToken{{Text: name}}
+	for i := 0; i+1 < len(params); i += 2 {
+		if params[i+1] == "" {
+			continue
+		}
+		tokens = append(tokens, ScriptToken{Text: fmt.Sprintf("-%s=%s", params[i], params[i+1])})
+	}
+	return joinTokens(tokens)
+}
+
+// failureExplainer busca, tras un fallo del núcleo, una causa más precisa que
+// el mensaje impreso (por ejemplo falta de espacio en el disco).
+type failureExplainer func() (ErrorCode, string, bool)
+
+// runTypedCommand valida el comando con las mismas reglas que
+// /api/scripts/validate y lo ejecuta con la sesión del cliente. Si algo
+// falla escribe la respuesta de error y devuelve false. Requiere coreMu
+// tomado: el endpoint se registra con ReadsCore o lo toma en escritura.
+func runTypedCommand(w http.ResponseWriter, r *http.Request, command string, explain failureExplainer) (CommandResult, bool) {
+	statement, err := LexCommand(command)
+	if err != nil || len(statement.Tokens) == 0 || statement.Command != command {
+		writeAPIError(w, http.StatusBadRequest, APIError{
+			Error:   "los valores contienen caracteres no permitidos",
+			Code:    ErrInvalidParameters,
+			Command: command,
+		})
+		return CommandResult{}, false
+	}
+
+	var problems []ScriptDiagnostic
+	for _, diagnostic := range validateStatement(statement) {
+		if diagnostic.Severity == SeverityError {
+			problems = append(problems, diagnostic)
+		}
+	}
+	if len(problems) > 0 {
+		writeAPIError(w, http.StatusBadRequest, APIError{
+			Error:       problems[0].Message,
+			Code:        problems[0].Code,
+			Command:     command,
+			Diagnostics: problems,
+		})
+		return CommandResult{}, false
+	}
+
+	result := runCommandLocked(usermanag.CallerFromRequest(w, r), io.Discard, command)
+	if !result.Success {
+		code, message := result.Outcome.Code, result.Outcome.Message
+		if explain != nil && code == ErrCommandFailed {
+			if betterCode, betterMessage, ok := explain(); ok {
+				code, message = betterCode, betterMessage
+			}
+		}
+
+		writeAPIError(w, httpStatusForCode(code), APIError{
+			Error:   message,
+			Code:    code,
+			Command: command,
+			Output:  result.Output,
+		})
+		return result, false
+	}
+
+	return result, true
+}
@@ -0,0 +1,317 @@
+package usermanag
+
+import (
+	"Backend/UserManagement"
+	"Backend/api/handlers/console"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SessionCookieName = "mia_session"
+	SessionHeaderName = "X-Session-Token"
+
+	// Una sesión que no se usa en este tiempo se descarta.
+	sessionIdleTimeout = 4 * time.Hour
+)
+
+// El núcleo (Analyzer, UserManagement, FileManagement) sigue trabajando con
+// UserManagement.CurrentSession, por eso cada cliente guarda su propia copia
+// y solo se instala en la global mientras se atiende su petición.
+type clientSession struct {
+	token    string
+	info     SessionInfo
+	install  func()
+	lastUsed time.Time
+}
+
+var (
+	sessionsMu sync.Mutex
+	sessions   = make(map[string]*clientSession)
+
+	globalSessionMu  sync.Mutex
+	installLoggedOut = captureGlobalSession()
+)
+
+// Caller representa a quien hace la petición. Permite ejecutar código del
+// núcleo con su sesión y recoger los login/logout que haga un comando.
+type Caller struct {
+	token string
+	w     http.ResponseWriter
+	// forked indica que el token es propio del Caller (ver Fork) y puede
+	// cambiar sin avisar al cliente.
+	forked bool
+}
+
+// CallerFromRequest usa w para enviar al cliente el token nuevo si un
+// comando hace login o logout, así que las cabeceras de w no pueden haberse
+// enviado todavía. Quien no tenga una respuesta así (trabajos en segundo
+// plano, SSE) debe usar Fork.
+func CallerFromRequest(w http.ResponseWriter, r *http.Request) *Caller {
+	return &Caller{token: tokenFromRequest(r), w: w}
+}
+
+func (c *Caller) Session() (*SessionInfo, bool) {
+	return lookupSession(c.token)
+}
+
+// Run instala la sesión del cliente, ejecuta fn y guarda en su sesión los
+// cambios que fn haya hecho (login o logout desde la consola).
+func (c *Caller) Run(fn func()) {
+	globalSessionMu.Lock()
+	defer globalSessionMu.Unlock()
+
+	before := installSessionLocked(c.token)
+	defer installLoggedOut()
+
+	fn()
+
+	after := currentGlobalSession()
+	if sameSession(before, after) {
+		return
+	}
+
+	// Sin respuesta donde escribir el token nuevo el cliente perdería su
+	// sesión, así que se conserva la que tenía.
+	if !c.forked && c.w == nil {
+		console.Printf("La sesión del cliente no cambia: no hay respuesta donde enviar el token nuevo\n")
+		return
+	}
+
+	if c.token != "" {
+		deleteSession(c.token)
+		c.token = ""
+	}
+
+	if after != nil {
+		c.token = storeSession(*after, captureGlobalSession())
+		if c.w != nil {
+			writeSessionToken(c.w, c.token)
+		}
+	} else if c.w != nil {
+		clearSessionToken(c.w)
+	}
+}
+
+// Fork devuelve un Caller con una copia de la sesión actual. Los login o
+// logout que haga no afectan al original; hay que liberarlo con Discard.
+func (c *Caller) Fork() *Caller {
+	sessionsMu.Lock()
+	session, ok := touchSessionLocked(c.token)
+	sessionsMu.Unlock()
+
+	if !ok {
+		return &Caller{forked: true}
+	}
+
+	return &Caller{token: storeSession(session.info, session.install), forked: true}
+}
+
+func (c *Caller) Discard() {
+	if c.token != "" {
+		deleteSession(c.token)
+		c.token = ""
+	}
+}
+
+// WithSession ejecuta fn con la sesión del cliente instalada en
+// UserManagement.CurrentSession. fn puede modificarla (por ejemplo la
+// partición a explorar); al terminar se descarta.
+func WithSession(r *http.Request, fn func()) {
+	globalSessionMu.Lock()
+	defer globalSessionMu.Unlock()
+
+	installSessionLocked(tokenFromRequest(r))
+	defer installLoggedOut()
+
+	fn()
+}
+
+func SessionFromRequest(r *http.Request) (*SessionInfo, bool) {
+	return lookupSession(tokenFromRequest(r))
+}
+
+func ActiveSessionCount() int {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	pruneSessionsLocked(time.Now())
+	return len(sessions)
+}
+
+func tokenFromRequest(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if token := r.Header.Get(SessionHeaderName); token != "" {
+		return token
+	}
+	if cookie, err := r.Cookie(SessionCookieName); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+func lookupSession(token string) (*SessionInfo, bool) {
+	if token == "" {
+		return nil, false
+	}
+
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	session, ok := touchSessionLocked(token)
+	if !ok {
+		return nil, false
+	}
+
+	info := session.info
+	return &info, true
+}
+
+// touchSessionLocked devuelve la sesión si no ha expirado y renueva su
+// plazo. Requiere sessionsMu tomado.
+func touchSessionLocked(token string) (*clientSession, bool) {
+	now := time.Now()
+
+	session, ok := sessions[token]
+	if !ok {
+		return nil, false
+	}
+	if sessionExpired(session, now) {
+		delete(sessions, token)
+		return nil, false
+	}
+
+	session.lastUsed = now
+	return session, true
+}
+
+func sessionExpired(session *clientSession, now time.Time) bool {
+	return now.Sub(session.lastUsed) > sessionIdleTimeout
+}
+
+// pruneSessionsLocked requiere sessionsMu tomado.
+func pruneSessionsLocked(now time.Time) {
+	for token, session := range sessions {
+		if sessionExpired(session, now) {
+			delete(sessions, token)
+		}
+	}
+}
+
+func storeSession(info SessionInfo, install func()) string {
+	token := newSessionToken()
+	now := time.Now()
+
+	sessionsMu.Lock()
+	pruneSessionsLocked(now)
+	sessions[token] = &clientSession{
+		token:    token,
+		info:     info,
+		install:  install,
+		lastUsed: now,
+	}
+	sessionsMu.Unlock()
+
+	return token
+}
+
+func deleteSession(token string) (*SessionInfo, bool) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	session, ok := sessions[token]
+	if !ok {
+		return nil, false
+	}
+	delete(sessions, token)
+
+	info := session.info
+	return &info, true
+}
+
+// installSessionLocked requiere globalSessionMu tomado.
+func installSessionLocked(token string) *SessionInfo {
+	installLoggedOut()
+
+	if token == "" {
+		return nil
+	}
+
+	sessionsMu.Lock()
+	session, ok := touchSessionLocked(token)
+	sessionsMu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	session.install()
+	info := session.info
+	return &info
+}
+
+func captureGlobalSession() func() {
+	saved := UserManagement.CurrentSession
+	return func() {
+		UserManagement.CurrentSession = saved
+	}
+}
+
+func currentGlobalSession() *SessionInfo {
+	if !UserManagement.IsLoggedIn() {
+		return nil
+	}
+
+	return &SessionInfo{
+		Username:    UserManagement.CurrentSession.Username,
+		UID:         UserManagement.CurrentSession.UID,
+		GID:         UserManagement.CurrentSession.GID,
+		PartitionID: UserManagement.CurrentSession.PartitionID,
+		IsActive:    true,
+		IsRoot:      UserManagement.CurrentSession.IsRoot,
+		Group:       UserManagement.CurrentSession.Group,
+	}
+}
+
+func sameSession(a, b *SessionInfo) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func newSessionToken() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("no se pudo generar token de sesión: %v", err))
+	}
+	return hex.EncodeToString(buf)
+}
+
+func writeSessionToken(w http.ResponseWriter, token string) {
+	w.Header().Set(SessionHeaderName, token)
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func clearSessionToken(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
@@ -0,0 +1,207 @@
+package usermanag
+
+import (
+	"Backend/UserManagement"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func resetSessions(t *testing.T) {
+	t.Helper()
+
+	sessionsMu.Lock()
+	sessions = make(map[string]*clientSession)
+	sessionsMu.Unlock()
+	UserManagement.CurrentSession = UserManagement.Session{}
+}
+
+// loginAs guarda una sesión como la dejaría un login desde la consola.
+func loginAs(username, partitionID string) string {
+	UserManagement.CurrentSession = UserManagement.Session{Username: username, PartitionID: partitionID, UID: 1, GID: 1, IsRoot: username == "root"}
+	token := storeSession(*currentGlobalSession(), captureGlobalSession())
+	UserManagement.CurrentSession = UserManagement.Session{}
+	return token
+}
+
+func TestTokenFromRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		bearer string
+		header string
+		cookie string
+		want   string
+	}{
+		{name: "sin token"},
+		{name: "cookie", cookie: "c", want: "c"},
+		{name: "cabecera antes que cookie", header: "h", cookie: "c", want: "h"},
+		{name: "Authorization antes que todo", bearer: "b", header: "h", cookie: "c", want: "b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.bearer != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			if tt.header != "" {
+				r.Header.Set(SessionHeaderName, tt.header)
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: SessionCookieName, Value: tt.cookie})
+			}
+
+			if got := tokenFromRequest(r); got != tt.want {
+				t.Errorf("token = %q, se esperaba %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallerRunLogin(t *testing.T) {
+	resetSessions(t)
+
+	w := httptest.NewRecorder()
+	caller := &Caller{w: w}
+	caller.Run(func() {
+		UserManagement.CurrentSession = UserManagement.Session{Username: "root", PartitionID: "A1", IsRoot: true}
+	})
+
+	token := w.Header().Get(SessionHeaderName)
+	if token == "" || token != caller.token {
+		t.Fatalf("token enviado %q, token del Caller %q", token, caller.token)
+	}
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].Value != token {
+		t.Errorf("cookies = %v", cookies)
+	}
+
+	session, ok := lookupSession(token)
+	if !ok || session.Username != "root" || session.PartitionID != "A1" {
+		t.Errorf("sesión guardada = %+v, %t", session, ok)
+	}
+	if UserManagement.IsLoggedIn() {
+		t.Error("la sesión global quedó instalada después de Run")
+	}
+}
+
+func TestCallerRunInstallsSession(t *testing.T) {
+	resetSessions(t)
+	token := loginAs("ana", "A1")
+
+	var during string
+	caller := &Caller{token: token, w: httptest.NewRecorder()}
+	caller.Run(func() {
+		during = UserManagement.CurrentSession.Username
+	})
+
+	if during != "ana" {
+		t.Errorf("usuario durante Run = %q, se esperaba ana", during)
+	}
+	if caller.token != token {
+		t.Error("el token cambió sin login ni logout")
+	}
+}
+
+func TestCallerRunLogout(t *testing.T) {
+	resetSessions(t)
+	token := loginAs("ana", "A1")
+
+	w := httptest.NewRecorder()
+	caller := &Caller{token: token, w: w}
+	caller.Run(func() {
+		UserManagement.CurrentSession = UserManagement.Session{}
+	})
+
+	if _, ok := lookupSession(token); ok {
+		t.Error("la sesión sigue activa después del logout")
+	}
+	if caller.token != "" {
+		t.Errorf("token del Caller = %q", caller.token)
+	}
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("la cookie no se borró: %v", cookies)
+	}
+}
+
+// Sin respuesta donde enviar el token nuevo, el cliente conserva el suyo.
+func TestCallerRunWithoutResponse(t *testing.T) {
+	resetSessions(t)
+	token := loginAs("ana", "A1")
+
+	caller := &Caller{token: token}
+	caller.Run(func() {
+		UserManagement.CurrentSession = UserManagement.Session{}
+	})
+
+	if caller.token != token {
+		t.Errorf("token = %q, se esperaba %q", caller.token, token)
+	}
+	if session, ok := lookupSession(token); !ok || session.Username != "ana" {
+		t.Error("se perdió la sesión del cliente")
+	}
+}
+
+func TestCallerFork(t *testing.T) {
+	resetSessions(t)
+	token := loginAs("ana", "A1")
+
+	original := &Caller{token: token, w: httptest.NewRecorder()}
+	fork := original.Fork()
+	if fork.token == "" || fork.token == token {
+		t.Fatalf("token del fork = %q", fork.token)
+	}
+
+	fork.Run(func() {
+		UserManagement.CurrentSession = UserManagement.Session{Username: "root", PartitionID: "A1", IsRoot: true}
+	})
+
+	if session, ok := original.Session(); !ok || session.Username != "ana" {
+		t.Errorf("el login del fork cambió la sesión original: %+v", session)
+	}
+	if session, ok := fork.Session(); !ok || session.Username != "root" {
+		t.Errorf("sesión del fork = %+v", session)
+	}
+
+	forkToken := fork.token
+	fork.Discard()
+	if _, ok := lookupSession(forkToken); ok {
+		t.Error("Discard no borró la sesión del fork")
+	}
+	if ActiveSessionCount() != 1 {
+		t.Errorf("sesiones activas = %d, se esperaba 1", ActiveSessionCount())
+	}
+}
+
+func TestSessionIdleTimeout(t *testing.T) {
+	resetSessions(t)
+	stale := loginAs("ana", "A1")
+	fresh := loginAs("root", "A1")
+
+	sessionsMu.Lock()
+	sessions[stale].lastUsed = time.Now().Add(-sessionIdleTimeout - time.Minute)
+	sessions[fresh].lastUsed = time.Now().Add(-sessionIdleTimeout + time.Minute)
+	sessionsMu.Unlock()
+
+	if _, ok := lookupSession(stale); ok {
+		t.Error("una sesión vencida sigue siendo válida")
+	}
+	if _, ok := lookupSession(fresh); !ok {
+		t.Error("una sesión vigente dejó de ser válida")
+	}
+
+	// lookupSession renovó el plazo de fresh.
+	sessionsMu.Lock()
+	renewed := time.Since(sessions[fresh].lastUsed) < time.Minute
+	sessionsMu.Unlock()
+	if !renewed {
+		t.Error("usar la sesión no renovó su plazo")
+	}
+
+	if forked := (&Caller{token: stale}).Fork(); forked.token != "" {
+		t.Error("Fork copió una sesión vencida")
+	}
+	if ActiveSessionCount() != 1 {
+		t.Errorf("sesiones activas = %d, se esperaba 1", ActiveSessionCount())
+	}
+}
@@ -1,12 +1,12 @@
 package usermanag
 
 import (
-	"Backend/DiskManagement"
 	"Backend/UserManagement"
+	"Backend/api/handlers/console"
+	"Backend/api/handlers/disk"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -45,6 +45,7 @@ type LoginResponse struct {
 	Success bool         `json:"success"`
 	Message string       `json:"message"`
 	Session *SessionInfo `json:"session,omitempty"`
+	Token   string       `json:"token,omitempty"`
 	Error   string       `json:"error,omitempty"`
 }
 
@@ -77,37 +78,42 @@ func HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Printf("🔍 Intentando login: %s en partición %s\n", req.User, req.IDParticion)
+	console.Printf("🔍 Intentando login: %s en partición %s\n", req.User, req.IDParticion)
+
+	globalSessionMu.Lock()
+	installLoggedOut()
 	UserManagement.Login(req.User, req.Password, req.IDParticion)
+	loggedIn := currentGlobalSession()
+	install := captureGlobalSession()
+	installLoggedOut()
+	globalSessionMu.Unlock()
+
+	if loggedIn != nil &&
+		loggedIn.Username == req.User &&
+		loggedIn.PartitionID == req.IDParticion {
 
-	if UserManagement.IsLoggedIn() &&
-		UserManagement.CurrentSession.Username == req.User &&
-		UserManagement.CurrentSession.PartitionID == req.IDParticion {
-
-		session := &SessionInfo{
-			Username:    UserManagement.CurrentSession.Username,
-			UID:         UserManagement.CurrentSession.UID,
-			GID:         UserManagement.CurrentSession.GID,
-			PartitionID: UserManagement.CurrentSession.PartitionID,
-			IsActive:    true,
-			IsRoot:      UserManagement.CurrentSession.IsRoot,
-			Group:       UserManagement.CurrentSession.Group,
+		if previous := tokenFromRequest(r); previous != "" {
+			deleteSession(previous)
 		}
 
+		token := storeSession(*loggedIn, install)
+		writeSessionToken(w, token)
+
 		response := LoginResponse{
 			Success: true,
 			Message: fmt.Sprintf("Login exitoso en partición %s", req.IDParticion),
-			Session: session,
+			Session: loggedIn,
+			Token:   token,
 		}
 
-		fmt.Printf("login exitoso: %s en %s (UID=%d, GID=%d, Root=%t)\n",
-			req.User, req.IDParticion, session.UID, session.GID, session.IsRoot)
+		console.Printf("login exitoso: %s en %s (UID=%d, GID=%d, Root=%t)\n",
+			req.User, req.IDParticion, loggedIn.UID, loggedIn.GID, loggedIn.IsRoot)
 		json.NewEncoder(w).Encode(response)
 	} else {
 		var errorDetail string
-		if !UserManagement.IsLoggedIn() {
+		if loggedIn == nil {
 			errorDetail = "Usuario o contraseña incorrectos, o usuario no existe en esta partición"
-		} else if UserManagement.CurrentSession.PartitionID != req.IDParticion {
+		} else if loggedIn.PartitionID != req.IDParticion {
 			errorDetail = "Error interno: sesión establecida en partición incorrecta"
 		} else {
 			errorDetail = "Error interno: sesión no coincide con usuario solicitado"
@@ -119,32 +125,18 @@ func HandleLogin(w http.ResponseWriter, r *http.Request) {
 			Message: errorDetail,
 		}
 
-		fmt.Printf(" Login falló: %s en %s - %s\n", req.User, req.IDParticion, errorDetail)
+		console.Printf(" Login falló: %s en %s - %s\n", req.User, req.IDParticion, errorDetail)
 		json.NewEncoder(w).Encode(response)
 	}
 }
 
 func validatePartitionForLogin(partitionID string) (bool, string) {
-	mountedPartitions := DiskManagement.GetMountedPartitions()
-
-	found := false
-	for _, partitions := range mountedPartitions {
-		for _, part := range partitions {
-			if strings.Trim(string(part.ID), "\x00") == partitionID {
-				found = true
-				break
-			}
-		}
-		if found {
-			break
-		}
-	}
-
-	if !found {
+	resolved, err := disk.ResolveMountID(partitionID)
+	if err != nil || !resolved.Mounted() {
 		return false, fmt.Sprintf("La partición %s no está montada o no existe", partitionID)
 	}
 
-	fmt.Printf("Partición %s está montada y disponible para login\n", partitionID)
+	console.Printf("Partición %s está montada y disponible para login\n", partitionID)
 	return true, ""
 }
 
@@ -152,7 +144,8 @@ func HandleLogout(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	if !UserManagement.IsLoggedIn() {
+	session, ok := deleteSession(tokenFromRequest(r))
+	if !ok {
 		response := map[string]interface{}{
 			"success": false,
 			"message": "No hay sesión activa",
@@ -161,33 +154,21 @@ func HandleLogout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	username := UserManagement.CurrentSession.Username
-
-	UserManagement.Logout()
+	clearSessionToken(w)
 
 	response := map[string]interface{}{
 		"success": true,
-		"message": fmt.Sprintf("Sesión cerrada para %s", username),
+		"message": fmt.Sprintf("Sesión cerrada para %s", session.Username),
 	}
 
-	fmt.Printf(" Logout exitoso: %s\n", username)
+	console.Printf(" Logout exitoso: %s\n", session.Username)
 	json.NewEncoder(w).Encode(response)
 }
 
 func GetCurrentSession(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if UserManagement.IsLoggedIn() {
-		session := SessionInfo{
-			Username:    UserManagement.CurrentSession.Username,
-			UID:         UserManagement.CurrentSession.UID,
-			GID:         UserManagement.CurrentSession.GID,
-			PartitionID: UserManagement.CurrentSession.PartitionID,
-			IsActive:    true,
-			IsRoot:      UserManagement.CurrentSession.IsRoot,
-			Group:       UserManagement.CurrentSession.Group,
-		}
-
+	if session, ok := SessionFromRequest(r); ok {
 		response := map[string]interface{}{
 			"success": true,
 			"session": session,
@@ -214,13 +195,18 @@ func GetAllUsers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	users, err := getUsersFromPartition(partitionID)
+	partitionID, ok := resolveMountedPartition(w, partitionID)
+	if !ok {
+		return
+	}
+
+	users, err := getUsersFromPartition(r, partitionID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error obteniendo usuarios: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	fmt.Printf("📋 Encontrados %d usuarios\n", len(users))
+	console.Printf("📋 Encontrados %d usuarios\n", len(users))
 	json.NewEncoder(w).Encode(users)
 }
 
@@ -236,20 +222,25 @@ func GetAllGroups(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	groups, err := getGroupsFromPartition(partitionID)
+	partitionID, ok := resolveMountedPartition(w, partitionID)
+	if !ok {
+		return
+	}
+
+	groups, err := getGroupsFromPartition(r, partitionID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error obteniendo grupos: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	fmt.Printf("📋 Encontrados %d grupos\n", len(groups))
+	console.Printf("📋 Encontrados %d grupos\n", len(groups))
 	json.NewEncoder(w).Encode(groups)
 }
 
-func getUsersFromPartition(partitionID string) ([]UserInfo, error) {
+func getUsersFromPartition(r *http.Request, partitionID string) ([]UserInfo, error) {
 	var users []UserInfo
 
-	records, err := readUserRecordsFromPartition(partitionID)
+	records, err := readUserRecordsFromPartition(r, partitionID)
 	if err != nil {
 		return nil, fmt.Errorf("error leyendo users.txt: %v", err)
 	}
@@ -270,7 +261,7 @@ func getUsersFromPartition(partitionID string) ([]UserInfo, error) {
 			}
 
 			users = append(users, user)
-			fmt.Printf("  👤 Usuario: %s (UID=%s, Grupo=%s, Estado=%s)\n",
+			console.Printf("  👤 Usuario: %s (UID=%s, Grupo=%s, Estado=%s)\n",
 				user.Username, user.UID, user.Group, user.Status)
 		}
 	}
@@ -278,10 +269,10 @@ func getUsersFromPartition(partitionID string) ([]UserInfo, error) {
 	return users, nil
 }
 
-func getGroupsFromPartition(partitionID string) ([]GroupInfo, error) {
+func getGroupsFromPartition(r *http.Request, partitionID string) ([]GroupInfo, error) {
 	var groups []GroupInfo
 
-	records, err := readUserRecordsFromPartition(partitionID)
+	records, err := readUserRecordsFromPartition(r, partitionID)
 	if err != nil {
 		return nil, fmt.Errorf("error leyendo users.txt: %v", err)
 	}
@@ -300,7 +291,7 @@ func getGroupsFromPartition(partitionID string) ([]GroupInfo, error) {
 			}
 
 			groups = append(groups, group)
-			fmt.Printf("  👥 Grupo: %s (GID=%s, Estado=%s)\n",
+			console.Printf("  👥 Grupo: %s (GID=%s, Estado=%s)\n",
 				group.Name, group.GID, group.Status)
 		}
 	}
@@ -308,29 +299,42 @@ func getGroupsFromPartition(partitionID string) ([]GroupInfo, error) {
 	return groups, nil
 }
 
-func readUserRecordsFromPartition(partitionID string) ([]UserManagement.UserRecord, error) {
+func readUserRecordsFromPartition(r *http.Request, partitionID string) ([]UserManagement.UserRecord, error) {
 
-	if !UserManagement.IsLoggedIn() {
+	session, ok := SessionFromRequest(r)
+	if !ok {
 		return nil, fmt.Errorf("se requiere sesión activa para leer users.txt")
 	}
 
-	originalPartition := UserManagement.CurrentSession.PartitionID
+	originalPartition := session.PartitionID
 	if originalPartition != partitionID {
 		return nil, fmt.Errorf("sesión activa en partición diferente (%s vs %s)", originalPartition, partitionID)
 	}
 
-	partition, file, err := UserManagement.FindMountedPartitionForLoggedUser(partitionID)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
+	return readUserRecordsAs(r, partitionID)
+}
 
-	sb, err := UserManagement.ReadSuperblock(file, partition)
-	if err != nil {
-		return nil, err
-	}
+func readUserRecordsAs(r *http.Request, partitionID string) ([]UserManagement.UserRecord, error) {
+	var records []UserManagement.UserRecord
+	var err error
+
+	WithSession(r, func() {
+		partition, file, findErr := UserManagement.FindMountedPartitionForLoggedUser(partitionID)
+		if findErr != nil {
+			err = findErr
+			return
+		}
+		defer file.Close()
+
+		sb, sbErr := UserManagement.ReadSuperblock(file, partition)
+		if sbErr != nil {
+			err = sbErr
+			return
+		}
+
+		records, err = UserManagement.ReadUserRecords(file, sb)
+	})
 
-	records, err := UserManagement.ReadUserRecords(file, sb)
 	if err != nil {
 		return nil, err
 	}
@@ -351,7 +355,8 @@ func GetPartitionUserInfo(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	if !UserManagement.IsLoggedIn() {
+	session, ok := SessionFromRequest(r)
+	if !ok {
 		response := map[string]interface{}{
 			"error":   "Se requiere login activo",
 			"message": "Para ver usuarios, primero debe hacer login en una partición",
@@ -361,27 +366,27 @@ func GetPartitionUserInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	users, err := getUsersFromCurrentSession()
+	users, err := getUsersFromSession(r, session)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error obteniendo usuarios: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	groups, err := getGroupsFromCurrentSession()
+	groups, err := getGroupsFromSession(r, session)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error obteniendo grupos: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	response := map[string]interface{}{
-		"partition_id": UserManagement.CurrentSession.PartitionID,
-		"session_user": UserManagement.CurrentSession.Username,
+		"partition_id": session.PartitionID,
+		"session_user": session.Username,
 		"session_info": map[string]interface{}{
-			"username": UserManagement.CurrentSession.Username,
-			"uid":      UserManagement.CurrentSession.UID,
-			"gid":      UserManagement.CurrentSession.GID,
-			"group":    UserManagement.CurrentSession.Group,
-			"is_root":  UserManagement.CurrentSession.IsRoot,
+			"username": session.Username,
+			"uid":      session.UID,
+			"gid":      session.GID,
+			"group":    session.Group,
+			"is_root":  session.IsRoot,
 		},
 		"total_users":  len(users),
 		"total_groups": len(groups),
@@ -389,28 +394,13 @@ func GetPartitionUserInfo(w http.ResponseWriter, r *http.Request) {
 		"groups":       groups,
 	}
 
-	fmt.Printf("📋 Enviando info de partición %s: %d usuarios, %d grupos\n",
-		UserManagement.CurrentSession.PartitionID, len(users), len(groups))
+	console.Printf("📋 Enviando info de partición %s: %d usuarios, %d grupos\n",
+		session.PartitionID, len(users), len(groups))
 	json.NewEncoder(w).Encode(response)
 }
 
-func getUsersFromCurrentSession() ([]UserInfo, error) {
-	if !UserManagement.IsLoggedIn() {
-		return nil, fmt.Errorf("no hay sesión activa")
-	}
-
-	partition, file, err := UserManagement.FindMountedPartitionForLoggedUser(UserManagement.CurrentSession.PartitionID)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	sb, err := UserManagement.ReadSuperblock(file, partition)
-	if err != nil {
-		return nil, err
-	}
-
-	records, err := UserManagement.ReadUserRecords(file, sb)
+func getUsersFromSession(r *http.Request, session *SessionInfo) ([]UserInfo, error) {
+	records, err := readUserRecordsAs(r, session.PartitionID)
 	if err != nil {
 		return nil, err
 	}
@@ -432,7 +422,7 @@ func getUsersFromCurrentSession() ([]UserInfo, error) {
 			}
 
 			users = append(users, user)
-			fmt.Printf("  Usuario encontrado: %s (UID=%s, Grupo=%s, Estado=%s)\n",
+			console.Printf("  Usuario encontrado: %s (UID=%s, Grupo=%s, Estado=%s)\n",
 				user.Username, user.UID, user.Group, user.Status)
 		}
 	}
@@ -440,23 +430,8 @@ func getUsersFromCurrentSession() ([]UserInfo, error) {
 	return users, nil
 }
 
-func getGroupsFromCurrentSession() ([]GroupInfo, error) {
-	if !UserManagement.IsLoggedIn() {
-		return nil, fmt.Errorf("no hay sesión activa")
-	}
-
-	partition, file, err := UserManagement.FindMountedPartitionForLoggedUser(UserManagement.CurrentSession.PartitionID)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	sb, err := UserManagement.ReadSuperblock(file, partition)
-	if err != nil {
-		return nil, err
-	}
-
-	records, err := UserManagement.ReadUserRecords(file, sb)
+func getGroupsFromSession(r *http.Request, session *SessionInfo) ([]GroupInfo, error) {
+	records, err := readUserRecordsAs(r, session.PartitionID)
 	if err != nil {
 		return nil, err
 	}
@@ -470,13 +445,13 @@ func getGroupsFromCurrentSession() ([]GroupInfo, error) {
 			}
 
 			group := GroupInfo{
-				GID:    record.UID, 
+				GID:    record.UID,
 				Name:   record.Group,
 				Status: status,
 			}
 
 			groups = append(groups, group)
-			fmt.Printf("  Grupo encontrado: %s (GID=%s, Estado=%s)\n",
+			console.Printf("  Grupo encontrado: %s (GID=%s, Estado=%s)\n",
 				group.Name, group.GID, group.Status)
 		}
 	}
@@ -497,7 +472,13 @@ func GetUsersForDebug(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !UserManagement.IsLoggedIn() || UserManagement.CurrentSession.PartitionID != partitionID {
+	partitionID, ok := resolveMountedPartition(w, partitionID)
+	if !ok {
+		return
+	}
+
+	session, ok := SessionFromRequest(r)
+	if !ok || session.PartitionID != partitionID {
 		response := map[string]interface{}{
 			"error":      "Se requiere login activo en la partición para ver usuarios",
 			"suggestion": "Haga login primero con: POST /api/login",
@@ -506,7 +487,7 @@ func GetUsersForDebug(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	users, err := getUsersFromCurrentSession()
+	users, err := getUsersFromSession(r, session)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error obteniendo usuarios: %v", err), http.StatusInternalServerError)
 		return
@@ -514,7 +495,7 @@ func GetUsersForDebug(w http.ResponseWriter, r *http.Request) {
 
 	response := map[string]interface{}{
 		"partition_id": partitionID,
-		"logged_user":  UserManagement.CurrentSession.Username,
+		"logged_user":  session.Username,
 		"users":        users,
 	}
 
@@ -527,31 +508,36 @@ func ValidatePartitionForUsers(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	mountedPartitions := DiskManagement.GetMountedPartitions()
-
-	found := false
-	for _, partitions := range mountedPartitions {
-		for _, part := range partitions {
-			if strings.Trim(string(part.ID), "\x00") == partitionID {
-				found = true
-				break
-			}
-		}
-		if found {
-			break
-		}
-	}
+	resolved, err := disk.ResolveMountID(partitionID)
+	found := err == nil && resolved.Mounted()
 
 	response := map[string]interface{}{
 		"partition_id":    partitionID,
 		"is_mounted":      found,
-		"is_formatted":    found, 
-		"ready_for_users": found,
+		"is_formatted":    found && resolved.Formatted(),
+		"ready_for_users": found && resolved.Formatted(),
 	}
 
-	if !found {
-		response["error"] = "Partición no encontrada o no montada"
+	if err != nil {
+		response["error"] = err.Error()
+	} else if !found {
+		response["error"] = "Partición no montada"
 	}
 
 	json.NewEncoder(w).Encode(response)
 }
+
+// resolveMountedPartition devuelve el ID de montaje tal como lo guarda el
+// núcleo o responde 404 si la partición no existe o no está montada.
+func resolveMountedPartition(w http.ResponseWriter, partitionID string) (string, bool) {
+	resolved, err := disk.ResolveMountID(partitionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return "", false
+	}
+	if !resolved.Mounted() {
+		http.Error(w, fmt.Sprintf("La partición %s no está montada", partitionID), http.StatusNotFound)
+		return "", false
+	}
+	return resolved.MountID, true
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"Backend/DiskManagement"
-	"Backend/UserManagement"
 	"Backend/Utils"
 	"Backend/api/handlers"
 	"Backend/api/handlers/disk"
@@ -31,6 +30,11 @@ func main() {
 
 	router := mux.NewRouter()
 
+	// Todo lo que lee discos, montajes o sesiones del núcleo va con
+	// handlers.ReadsCore para no ver el estado temporal de una simulación.
+	// Los endpoints de comandos, lotes, fsck y escritura de archivos toman
+	// el candado por su cuenta.
+
 	// 🌐 CONFIGURACIÓN CORS PARA AWS S3 + EC2 COMMUNICATION
 	// Esto permite que tu frontend en S3 haga peticiones a tu backend en EC2
 	c := cors.New(cors.Options{
@@ -46,42 +50,75 @@ func main() {
 			"*",                                            // 🚨 Permite todo (solo para desarrollo)
 		},
 		// 📡 Métodos HTTP permitidos desde el frontend
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		// 📋 Headers permitidos en las peticiones
 		AllowedHeaders: []string{"*"},
+		// 🔑 El token de sesión viaja en este header (además de Authorization)
+		ExposedHeaders: []string{usermanag.SessionHeaderName, "Content-Disposition", "Content-Range", "ETag", "Last-Modified"},
 		// 🔒 Sin credenciales (cookies, auth headers)
 		AllowCredentials: false,
 	})
 
-	router.HandleFunc("/api/health", healthCheck).Methods("GET")
-	router.HandleFunc("/api/system-status", getSystemStatus).Methods("GET")
+	router.HandleFunc("/api/health", handlers.ReadsCore(healthCheck)).Methods("GET")
+	router.HandleFunc("/api/system-status", handlers.ReadsCore(getSystemStatus)).Methods("GET")
 
 	router.HandleFunc("/api/execute-command", handlers.ExecuteCommand).Methods("POST")
 	router.HandleFunc("/api/streaming-batch", handlers.StreamingBatchExecute).Methods("POST")
 
-	router.HandleFunc("/api/login", usermanag.HandleLogin).Methods("POST")
-	router.HandleFunc("/api/logout", usermanag.HandleLogout).Methods("POST")
-	router.HandleFunc("/api/session", usermanag.GetCurrentSession).Methods("GET")
-
-	router.HandleFunc("/api/users/{partitionId}", usermanag.GetAllUsers).Methods("GET")
-	router.HandleFunc("/api/groups/{partitionId}", usermanag.GetAllGroups).Methods("GET")
-	router.HandleFunc("/api/partition-info", usermanag.GetPartitionUserInfo).Methods("GET")
-	router.HandleFunc("/api/validate-partition/{partitionId}", usermanag.ValidatePartitionForUsers).Methods("GET")
-
-	router.HandleFunc("/api/disks", disk.GetAllDisks).Methods("GET")
-	router.HandleFunc("/api/partitions/{diskId}", disk.GetAllPartitions).Methods("GET")
-
-	router.HandleFunc("/api/disk-details/{diskId}", getDiskDetails).Methods("GET")
-	router.HandleFunc("/api/partition-details/{partitionId}", getPartitionDetails).Methods("GET")
-
-	router.HandleFunc("/api/filesystem/{partitionId}", filemanag.GetAllFiles).Methods("GET")
-	router.HandleFunc("/api/file-content/{partitionId}", filemanag.GetFileContent).Methods("GET")
-
-	router.HandleFunc("/api/global-scan", filemanag.GetGlobalScan).Methods("GET")
-	router.HandleFunc("/api/explorable-partitions", filemanag.GetAllExplorablePartitions).Methods("GET")
-
-	router.HandleFunc("/api/mounted-partitions", getMountedPartitions).Methods("GET")
-	router.HandleFunc("/api/debug/users/{partitionId}", usermanag.GetUsersForDebug).Methods("GET")
+	router.HandleFunc("/api/scripts/validate", handlers.ValidateScript).Methods("POST")
+	router.HandleFunc("/api/scripts/dry-run", handlers.DryRunScript).Methods("POST")
+
+	router.HandleFunc("/api/jobs", handlers.SubmitJob).Methods("POST")
+	router.HandleFunc("/api/jobs/{id}", handlers.GetJob).Methods("GET")
+	router.HandleFunc("/api/jobs/{id}", handlers.CancelJob).Methods("DELETE")
+
+	router.HandleFunc("/api/login", handlers.ReadsCore(usermanag.HandleLogin)).Methods("POST")
+	router.HandleFunc("/api/logout", handlers.ReadsCore(usermanag.HandleLogout)).Methods("POST")
+	router.HandleFunc("/api/session", handlers.ReadsCore(usermanag.GetCurrentSession)).Methods("GET")
+
+	router.HandleFunc("/api/users/{partitionId}", handlers.ReadsCore(usermanag.GetAllUsers)).Methods("GET")
+	router.HandleFunc("/api/groups/{partitionId}", handlers.ReadsCore(usermanag.GetAllGroups)).Methods("GET")
+	router.HandleFunc("/api/partition-info", handlers.ReadsCore(usermanag.GetPartitionUserInfo)).Methods("GET")
+	router.HandleFunc("/api/validate-partition/{partitionId}", handlers.ReadsCore(usermanag.ValidatePartitionForUsers)).Methods("GET")
+
+	router.HandleFunc("/api/disks", handlers.ReadsCore(disk.GetAllDisks)).Methods("GET")
+	router.HandleFunc("/api/disks", handlers.ReadsCore(handlers.CreateDisk)).Methods("POST")
+	router.HandleFunc("/api/disks/{diskId}", handlers.ReadsCore(handlers.DeleteDisk)).Methods("DELETE")
+	router.HandleFunc("/api/disks/{diskId}/layout", handlers.ReadsCore(disk.GetDiskLayout)).Methods("GET")
+	router.HandleFunc("/api/partitions/{diskId}", handlers.ReadsCore(disk.GetAllPartitions)).Methods("GET")
+	router.HandleFunc("/api/partitions/{diskId}", handlers.ReadsCore(handlers.CreatePartition)).Methods("POST")
+	router.HandleFunc("/api/partitions/{id}/superblock", handlers.ReadsCore(disk.GetPartitionSuperblock)).Methods("GET")
+	router.HandleFunc("/api/partitions/{id}/bitmaps", handlers.ReadsCore(disk.GetPartitionBitmaps)).Methods("GET")
+	router.HandleFunc("/api/partitions/{id}/fsck", handlers.CheckFilesystem).Methods("POST")
+	router.HandleFunc("/api/partitions/{diskId}/{name}", handlers.ReadsCore(handlers.DeletePartition)).Methods("DELETE")
+	router.HandleFunc("/api/partitions/{diskId}/{name}", handlers.ReadsCore(handlers.ResizePartition)).Methods("PATCH")
+
+	router.HandleFunc("/api/inspect/disks/{diskId}/mbr", handlers.ReadsCore(disk.InspectMBR)).Methods("GET")
+	router.HandleFunc("/api/inspect/disks/{diskId}/ebr", handlers.ReadsCore(disk.InspectEBR)).Methods("GET")
+	router.HandleFunc("/api/inspect/disks/{diskId}/hex", handlers.ReadsCore(disk.InspectHex)).Methods("GET")
+	router.HandleFunc("/api/inspect/partitions/{id}/inodes/{n}", handlers.ReadsCore(disk.InspectInode)).Methods("GET")
+	router.HandleFunc("/api/inspect/partitions/{id}/blocks/{n}", handlers.ReadsCore(disk.InspectBlock)).Methods("GET")
+
+	router.HandleFunc("/api/disk-details/{diskId}", handlers.ReadsCore(getDiskDetails)).Methods("GET")
+	router.HandleFunc("/api/partition-details/{partitionId}", handlers.ReadsCore(getPartitionDetails)).Methods("GET")
+
+	router.HandleFunc("/api/filesystem/{partitionId}", handlers.ReadsCore(filemanag.GetAllFiles)).Methods("GET")
+	router.HandleFunc("/api/file-content/{partitionId}", handlers.ReadsCore(filemanag.GetFileContent)).Methods("GET")
+	router.HandleFunc("/api/files/{partitionId}/raw", handlers.ReadsCore(filemanag.GetFileRaw)).Methods("GET")
+	router.HandleFunc("/api/files/{partitionId}/download", handlers.ReadsCore(filemanag.DownloadFile)).Methods("GET")
+	router.HandleFunc("/api/files/{partitionId}", handlers.UploadFile).Methods("PUT")
+
+	router.HandleFunc("/api/fs/{partitionId}/mkdir", handlers.ReadsCore(handlers.MakeDirectory)).Methods("POST")
+	router.HandleFunc("/api/fs/{partitionId}/rename", handlers.RenamePath).Methods("POST")
+	router.HandleFunc("/api/fs/{partitionId}/move", handlers.MovePath).Methods("POST")
+	router.HandleFunc("/api/fs/{partitionId}/copy", handlers.CopyPath).Methods("POST")
+	router.HandleFunc("/api/fs/{partitionId}", handlers.RemovePath).Methods("DELETE")
+
+	router.HandleFunc("/api/global-scan", handlers.ReadsCore(filemanag.GetGlobalScan)).Methods("GET")
+	router.HandleFunc("/api/explorable-partitions", handlers.ReadsCore(filemanag.GetAllExplorablePartitions)).Methods("GET")
+
+	router.HandleFunc("/api/mounted-partitions", handlers.ReadsCore(getMountedPartitions)).Methods("GET")
+	router.HandleFunc("/api/debug/users/{partitionId}", handlers.ReadsCore(usermanag.GetUsersForDebug)).Methods("GET")
 
 	handler := c.Handler(router)
 
@@ -115,20 +152,23 @@ func healthCheck(w http.ResponseWriter, r *http.Request) {
 		mountedCount += len(partitions)
 	}
 
+	session, loggedIn := usermanag.SessionFromRequest(r)
+
 	response := map[string]interface{}{
 		"status":             "OK",
 		"message":            "Backend funcionando correctamente",
 		"version":            "2.0.0-modularized",
 		"mounted_partitions": mountedCount,
-		"session_active":     UserManagement.IsLoggedIn(),
+		"session_active":     loggedIn,
+		"active_sessions":    usermanag.ActiveSessionCount(),
 		"modules_loaded":     []string{"disk", "filemanag", "usermanag"},
 		"legacy_deprecated":  true,
 	}
 
-	if UserManagement.IsLoggedIn() {
-		response["current_user"] = UserManagement.CurrentSession.Username
-		response["partition_id"] = UserManagement.CurrentSession.PartitionID
-		response["is_root"] = UserManagement.CurrentSession.IsRoot
+	if loggedIn {
+		response["current_user"] = session.Username
+		response["partition_id"] = session.PartitionID
+		response["is_root"] = session.IsRoot
 	}
 
 	json.NewEncoder(w).Encode(response)
@@ -154,26 +194,29 @@ func getSystemStatus(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	session, loggedIn := usermanag.SessionFromRequest(r)
+
 	response := map[string]interface{}{
 		"api_version":          "2.0.0-modularized",
 		"modules_active":       []string{"disk", "filemanag", "usermanag"},
 		"legacy_handlers":      "deprecated",
 		"total_disks":          diskCount,
 		"total_partitions":     totalPartitions,
-		"session_active":       UserManagement.IsLoggedIn(),
+		"session_active":       loggedIn,
+		"active_sessions":      usermanag.ActiveSessionCount(),
 		"disk_details":         diskDetails,
 		"system_ready":         true,
 		"aws_deployment_ready": true,
 	}
 
-	if UserManagement.IsLoggedIn() {
+	if loggedIn {
 		response["current_session"] = map[string]interface{}{
-			"username":     UserManagement.CurrentSession.Username,
-			"partition_id": UserManagement.CurrentSession.PartitionID,
-			"group":        UserManagement.CurrentSession.Group,
-			"is_root":      UserManagement.CurrentSession.IsRoot,
-			"uid":          UserManagement.CurrentSession.UID,
-			"gid":          UserManagement.CurrentSession.GID,
+			"username":     session.Username,
+			"partition_id": session.PartitionID,
+			"group":        session.Group,
+			"is_root":      session.IsRoot,
+			"uid":          session.UID,
+			"gid":          session.GID,
 		}
 	}
 
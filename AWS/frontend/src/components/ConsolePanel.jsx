@@ -1,5 +1,5 @@
 import { useState } from "react";
-import { API_BASE_URL } from "../config/api";
+import { API_BASE_URL, authHeaders, storeSessionToken } from "../config/api";
 
 export default function ConsolePanel() {
   const [command, setCommand] = useState("");
@@ -18,11 +18,12 @@ export default function ConsolePanel() {
       try {
         const response = await fetch(`${API_BASE_URL}/api/execute-command`, {
           method: 'POST',
-          headers: { 
+          headers: authHeaders({
             'Content-Type': 'application/json'
-          },
+          }),
           body: JSON.stringify({ command: command.trim() })
         });
+        storeSessionToken(response);
         
         const result = await response.json();
         console.log('Command result:', result);
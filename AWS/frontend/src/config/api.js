@@ -8,5 +8,19 @@ const getApiBaseUrl = () => {
 
 export const API_BASE_URL = getApiBaseUrl();
 
+export const SESSION_TOKEN_KEY = 'mia_token';
+
+export const authHeaders = (headers = {}) => {
+  const token = localStorage.getItem(SESSION_TOKEN_KEY);
+  return token ? { ...headers, Authorization: `Bearer ${token}` } : headers;
+};
+
+export const storeSessionToken = (response) => {
+  const token = response.headers.get('X-Session-Token');
+  if (token) {
+    localStorage.setItem(SESSION_TOKEN_KEY, token);
+  }
+};
+
 console.log('🌐 Environment:', import.meta.env.MODE);
 console.log('🌐 API Base URL:', API_BASE_URL);
\ No newline at end of file
@@ -1,6 +1,6 @@
 import { useState, useEffect, useRef } from "react";
 import { useNavigate, useLocation } from "react-router-dom";
-import { API_BASE_URL } from "../config/api";
+import { API_BASE_URL, SESSION_TOKEN_KEY, authHeaders, storeSessionToken } from "../config/api";
 
 export default function Console() {
   const navigate = useNavigate();
@@ -18,7 +18,9 @@ export default function Console() {
 
   const checkActiveSession = async () => {
     try {
-      const response = await fetch(`${API_BASE_URL}/api/session`);
+      const response = await fetch(`${API_BASE_URL}/api/session`, {
+        headers: authHeaders(),
+      });
       if (response.ok) {
         const data = await response.json();
         if (data.success && data.session) {
@@ -90,13 +92,15 @@ export default function Console() {
 
     try {
       const response = await fetch(`${API_BASE_URL}/api/logout`, {
-        method: 'POST'
+        method: 'POST',
+        headers: authHeaders(),
       });
       
       if (response.ok) {
         const data = await response.json();
         setSessionInfo(null);
         localStorage.removeItem('mia_session');
+        localStorage.removeItem(SESSION_TOKEN_KEY);
         addOutput({
           type: "success",
           content: ` ${data.message}`,
@@ -136,11 +140,12 @@ export default function Console() {
     try {
       const response = await fetch(`${API_BASE_URL}/api/execute-command`, {
         method: "POST",
-        headers: {
+        headers: authHeaders({
           "Content-Type": "application/json",
-        },
+        }),
         body: JSON.stringify({ command: currentCommand }),
       });
+      storeSessionToken(response);
 
       if (response.ok) {
         const data = await response.json();
@@ -1,6 +1,6 @@
 import { useState, useEffect } from "react";
 import { useNavigate } from "react-router-dom";
-import { API_BASE_URL } from "../config/api";
+import { API_BASE_URL, authHeaders } from "../config/api";
 
 export default function FileManagement() {
   const navigate = useNavigate();
@@ -107,7 +107,8 @@ export default function FileManagement() {
       setFilesError(null);
       
       const response = await fetch(
-        `${API_BASE_URL}/api/filesystem/${currentPartition.id}?path=${encodeURIComponent(path)}`
+        `${API_BASE_URL}/api/filesystem/${currentPartition.id}?path=${encodeURIComponent(path)}`,
+        { headers: authHeaders() }
       );
       
       if (response.ok) {
@@ -145,7 +146,8 @@ export default function FileManagement() {
       setContentError(null);
       
       const response = await fetch(
-        `${API_BASE_URL}/api/file-content/${currentPartition.id}?path=${encodeURIComponent(filePath)}`
+        `${API_BASE_URL}/api/file-content/${currentPartition.id}?path=${encodeURIComponent(filePath)}`,
+        { headers: authHeaders() }
       );
       
       if (response.ok) {
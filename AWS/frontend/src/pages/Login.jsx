@@ -1,6 +1,6 @@
 import { useState, useEffect } from "react";
 import { useNavigate, Link } from "react-router-dom";
-import { API_BASE_URL } from "../config/api";
+import { API_BASE_URL, SESSION_TOKEN_KEY, authHeaders } from "../config/api";
 
 export default function Login() {
   const navigate = useNavigate();
@@ -18,7 +18,9 @@ export default function Login() {
 
   const checkExistingSession = async () => {
     try {
-      const response = await fetch(`${API_BASE_URL}/api/session`);
+      const response = await fetch(`${API_BASE_URL}/api/session`, {
+        headers: authHeaders(),
+      });
       if (response.ok) {
         const data = await response.json();
         if (data.success && data.session) {
@@ -70,6 +72,7 @@ export default function Login() {
       if (data.success) {
         
         localStorage.setItem('mia_session', JSON.stringify(data.session));
+        localStorage.setItem(SESSION_TOKEN_KEY, data.token);
         
         navigate("/management");
       } else {
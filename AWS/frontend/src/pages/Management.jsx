@@ -1,6 +1,6 @@
 import { useState, useEffect } from "react";
 import { useNavigate, Link } from "react-router-dom";
-import { API_BASE_URL } from "../config/api";
+import { API_BASE_URL, SESSION_TOKEN_KEY, authHeaders } from "../config/api";
 
 export default function Management() {
   const navigate = useNavigate();
@@ -69,7 +69,9 @@ export default function Management() {
     setError("");
     try {
       
-      const response = await fetch(`${API_BASE_URL}/api/filesystem/${partitionId}?path=${encodeURIComponent(path)}`);
+      const response = await fetch(`${API_BASE_URL}/api/filesystem/${partitionId}?path=${encodeURIComponent(path)}`, {
+        headers: authHeaders(),
+      });
       if (!response.ok) {
         if (response.status === 401) {
           setError("Sesión expirada. Por favor, inicie sesión nuevamente.");
@@ -113,7 +115,9 @@ export default function Management() {
   const fetchFileContent = async (partitionId, filePath) => {
     try {
       
-      const response = await fetch(`${API_BASE_URL}/api/file-content/${partitionId}?path=${encodeURIComponent(filePath)}`);
+      const response = await fetch(`${API_BASE_URL}/api/file-content/${partitionId}?path=${encodeURIComponent(filePath)}`, {
+        headers: authHeaders(),
+      });
       if (!response.ok) {
         throw new Error(`HTTP error! status: ${response.status}`);
       }
@@ -221,16 +225,19 @@ export default function Management() {
   const handleLogout = async () => {
     try {
       const response = await fetch(`${API_BASE_URL}/api/logout`, {
-        method: 'POST'
+        method: 'POST',
+        headers: authHeaders(),
       });
       
       if (response.ok) {
         localStorage.removeItem('mia_session');
+        localStorage.removeItem(SESSION_TOKEN_KEY);
         navigate("/");
       }
     } catch (err) {
       console.error("Error during logout:", err);
       localStorage.removeItem('mia_session');
+      localStorage.removeItem(SESSION_TOKEN_KEY);
       navigate("/");
     }
   };
@@ -581,6 +588,31 @@ export default function Management() {
                               )}
                             </div>
                           </div>
+                          {partition.logical_partitions?.length > 0 && (
+                            <div className="mt-4 ml-4 space-y-2 border-l border-white/20 pl-4">
+                              <p className="text-xs text-gray-400">Particiones lógicas</p>
+                              {partition.logical_partitions.map(logical => (
+                                <div
+                                  key={logical.id}
+                                  onClick={(e) => { e.stopPropagation(); handlePartitionSelect(logical); }}
+                                  className={`flex justify-between items-center bg-white/5 rounded p-3 hover:bg-white/15 ${
+                                    selectedPartition?.id === logical.id ? "ring-2 ring-blue-400" : ""
+                                  }`}
+                                >
+                                  <div>
+                                    <p className="text-sm font-medium">ID: {logical.id}</p>
+                                    <p className="text-xs text-gray-300">{logical.name} · inicio {logical.start} · {logical.fit}</p>
+                                  </div>
+                                  <div className="text-right">
+                                    <p className="text-xs text-gray-300 font-mono">{logical.size}</p>
+                                    <p className={`text-xs ${logical.status === "Montada" ? "text-green-400" : "text-red-400"}`}>
+                                      {logical.status} · {logical.filesystem}
+                                    </p>
+                                  </div>
+                                </div>
+                              ))}
+                            </div>
+                          )}
                         </div>
                       ))}
                     </div>